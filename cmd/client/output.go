@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	accessClient "github.com/piyush1104/access/pkg/client"
+	"github.com/piyush1104/access/pkg/policy"
+)
+
+// output prints results as aligned tables or as JSON
+type output struct {
+	json bool
+	w    io.Writer
+}
+
+func newOutput(format string, w io.Writer) (*output, error) {
+	switch format {
+	case "table":
+		return &output{w: w}, nil
+	case "json":
+		return &output{json: true, w: w}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+func (o *output) encode(v interface{}) error {
+	enc := json.NewEncoder(o.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// table prints a header and rows aligned in columns
+func (o *output) table(header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			if i > 0 {
+				fmt.Fprint(tw, "\t")
+			}
+			fmt.Fprint(tw, cell)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+func (o *output) decisions(decisions []decision) error {
+	if o.json {
+		return o.encode(decisions)
+	}
+	rows := make([][]string, 0, len(decisions))
+	for _, d := range decisions {
+		verdict := "deny"
+		switch {
+		case d.Error != "":
+			verdict = "error: " + d.Error
+		case d.Allowed:
+			verdict = "allow"
+		}
+		rows = append(rows, []string{d.Subject, d.Resource, d.Action, verdict})
+	}
+	return o.table([]string{"SUBJECT", "RESOURCE", "ACTION", "VERDICT"}, rows)
+}
+
+func (o *output) policies(policies []accessClient.Policy) error {
+	if o.json {
+		return o.encode(policies)
+	}
+	rows := make([][]string, 0, len(policies))
+	for _, p := range policies {
+		rows = append(rows, []string{p.Subject, p.Resource, p.Action})
+	}
+	return o.table([]string{"SUBJECT", "RESOURCE", "ACTION"}, rows)
+}
+
+func (o *output) roles(roles []accessClient.RoleAssignment) error {
+	if o.json {
+		return o.encode(roles)
+	}
+	rows := make([][]string, 0, len(roles))
+	for _, r := range roles {
+		rows = append(rows, []string{r.Subject, r.Role})
+	}
+	return o.table([]string{"SUBJECT", "ROLE"}, rows)
+}
+
+func (o *output) resourceTypes(types []accessClient.ResourceType) error {
+	if o.json {
+		return o.encode(types)
+	}
+	rows := make([][]string, 0, len(types))
+	for _, t := range types {
+		source := "store"
+		if t.Static {
+			source = "config"
+		}
+		rows = append(rows, []string{t.Name, t.Prefix, strings.Join(t.Actions, ","), source})
+	}
+	return o.table([]string{"NAME", "PREFIX", "ACTIONS", "SOURCE"}, rows)
+}
+
+// imported prints the rules an import changed as a diff
+func (o *output) imported(result *accessClient.ImportResult) error {
+	if o.json {
+		return o.encode(result)
+	}
+	if err := o.diff(result.Added, result.Removed); err != nil {
+		return err
+	}
+	summary := "dry run"
+	switch {
+	case result.Applied:
+		summary = "applied"
+	case len(result.Added)+len(result.Removed) == 0:
+		summary = "up to date"
+	}
+	_, err := fmt.Fprintf(o.w, "%d added, %d removed, %s\n", len(result.Added), len(result.Removed), summary)
+	return err
+}
+
+// bulk prints the rules a bulk request changed as a diff, or the invalid
+// rules of those sent
+func (o *output) bulk(result *accessClient.BulkResult, sent []accessClient.PolicyRule) error {
+	if o.json {
+		return o.encode(result)
+	}
+	if len(result.Errors) > 0 {
+		for _, e := range result.Errors {
+			rule := fmt.Sprintf("rule %d", e.Index)
+			if e.Index >= 0 && e.Index < len(sent) {
+				rule = policy.Rule{PType: sent[e.Index].PType, Values: sent[e.Index].Values}.String()
+			}
+			if _, err := fmt.Fprintf(o.w, "%s: %s\n", rule, e.Message); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(o.w, "%d invalid rules, nothing changed\n", len(result.Errors))
+		return err
+	}
+	if err := o.diff(result.Added, result.Removed); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(o.w, "%d added, %d removed\n", len(result.Added), len(result.Removed))
+	return err
+}
+
+func (o *output) diff(added, removed []accessClient.PolicyRule) error {
+	diff := &policy.Diff{}
+	for _, r := range removed {
+		diff.Remove = append(diff.Remove, policy.Rule{PType: r.PType, Values: r.Values})
+	}
+	for _, r := range added {
+		diff.Add = append(diff.Add, policy.Rule{PType: r.PType, Values: r.Values})
+	}
+	_, err := fmt.Fprint(o.w, diff)
+	return err
+}
+
+// whatIf prints the decisions a change flips out of the ones probed
+func (o *output) whatIf(result *accessClient.WhatIfResult) error {
+	if o.json {
+		return o.encode(result)
+	}
+	verdict := func(allowed bool) string {
+		if allowed {
+			return "allow"
+		}
+		return "deny"
+	}
+	rows := make([][]string, 0, len(result.Changes))
+	for _, c := range result.Changes {
+		rows = append(rows, []string{c.Subject, c.Resource, c.Action, verdict(c.Before), verdict(c.After)})
+	}
+	if err := o.table([]string{"SUBJECT", "RESOURCE", "ACTION", "BEFORE", "AFTER"}, rows); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(o.w, "%d of %d decisions change\n", len(result.Changes), result.Probed)
+	return err
+}
+
+func (o *output) change(changed bool) error {
+	if o.json {
+		return o.encode(map[string]bool{"changed": changed})
+	}
+	if changed {
+		_, err := fmt.Fprintln(o.w, "changed")
+		return err
+	}
+	_, err := fmt.Fprintln(o.w, "unchanged")
+	return err
+}
+
+func (o *output) health(err error) error {
+	status := "serving"
+	if err != nil {
+		status = err.Error()
+	}
+	if o.json {
+		return o.encode(map[string]interface{}{"serving": err == nil, "status": status})
+	}
+	_, werr := fmt.Fprintln(o.w, status)
+	return werr
+}
@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	auth "github.com/100mslive/auth/client"
 	"github.com/100mslive/packages/conf"
 	"github.com/100mslive/packages/log"
 	"github.com/100mslive/packages/version"
 	accessServer "github.com/piyush1104/access/pkg/server"
+	"github.com/piyush1104/access/pkg/tracing"
 )
 
 var (
@@ -40,6 +45,10 @@ func main() {
 	serverConfig := accessServer.DefaultConfig()
 	config.Register("server", serverConfig)
 
+	// tracing
+	tracingConfig := tracing.DefaultConfig()
+	config.Register("tracing", tracingConfig)
+
 	// log
 	logConfig := log.DefaultConfig()
 	config.Register("log", logConfig)
@@ -50,14 +59,55 @@ func main() {
 
 	// Init Log
 	log.Init(logConfig)
+
+	if ok, err := policyCommand(serverConfig); ok {
+		if err != nil {
+			log.Errorf("Policy command failed %v", err)
+			os.Exit(1)
+		}
+		return
+	}
 	ctx := context.Background()
 
-	server := accessServer.New(serverConfig, accessServer.WithAuth(auth.New(authConfig,
-		auth.WithMetrics(true),
-		auth.WithRetry(true),
-		auth.WithTracing(true))))
+	shutdownTracing, err := tracing.Init(ctx, AppName, tracingConfig)
+	if err != nil {
+		log.Panicf("Failed to init tracing %v", err)
+	}
+
+	server := accessServer.New(serverConfig,
+		accessServer.WithAuth(auth.New(authConfig,
+			auth.WithMetrics(true),
+			auth.WithRetry(true),
+			auth.WithTracing(true))),
+		accessServer.WithTracing(tracingConfig.Enabled()))
+
+	// drain and stop on SIGINT or SIGTERM
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		<-signals
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(serverConfig.ShutdownTimeout)*time.Second)
+		defer cancel()
+		if err := server.Stop(ctx); err != nil {
+			log.Errorf("Failed to stop server %v", err)
+		}
+	}()
+
+	// apply config changes live, once Start has set the server up
+	watchConfig(server, stopped)
 
 	if err := server.Start(ctx); err != nil {
 		log.Panicf("Failed to start server %v", err)
 	}
+	<-stopped
+
+	// flush the spans of the last RPCs
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		log.Errorf("Failed to flush traces %v", err)
+	}
 }
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/piyush1104/access/pkg/policy"
+	accessServer "github.com/piyush1104/access/pkg/server"
+)
+
+// offline policy commands, run against the policy store instead of serving
+var (
+	flagExport  = flag.String("export", "", "export the complete policy to a file, - for stdout, and exit")
+	flagImport  = flag.String("import", "", "import a complete policy from a file, - for stdin, and exit")
+	flagFormat  = flag.String("format", "", "policy format: csv, json or yaml, by default from the file extension")
+	flagReplace = flag.Bool("replace", false, "import removes the rules the file does not have instead of merging")
+	flagDryRun  = flag.Bool("dry-run", false, "import or sync only prints the changes")
+	flagSync    = flag.String("sync", "", "sync the policy with the policy files of a directory and exit")
+	flagForce   = flag.Bool("force", false, "sync also removes the rules it did not add")
+	flagTest    = flag.String("test", "", "decide the YAML test cases of a file with -policy, no store needed, and exit")
+	flagLint    = flag.Bool("lint", false, "check the model and the policy, of -policy or the store, and exit")
+	flagJSON    = flag.Bool("json", false, "lint prints the findings as JSON")
+	flagPolicy  = flag.String("policy", "", "policy file or directory of policy files to test or lint")
+	flagModel   = flag.String("model", "", "model to test or lint, by default the configured one")
+)
+
+// policyCommand runs the offline policy command selected by flags, reporting
+// whether there was one
+func policyCommand(config *accessServer.Config) (bool, error) {
+	switch {
+	case countSet(*flagExport, *flagImport, *flagSync, *flagTest) > 1 || (*flagLint && countSet(*flagExport, *flagImport, *flagSync, *flagTest) > 0):
+		return true, errors.New("only one of -export, -import, -sync, -test and -lint may be set")
+	case *flagExport != "":
+		return true, exportPolicies(*flagExport)
+	case *flagImport != "":
+		return true, importPolicies(*flagImport, config)
+	case *flagSync != "":
+		return true, syncPolicies(*flagSync, config)
+	case *flagTest != "":
+		return true, testPolicies(*flagTest, modelPath(config), *flagPolicy)
+	case *flagLint:
+		return true, lintPolicies(modelPath(config), *flagPolicy, config.Lint)
+	}
+	return false, nil
+}
+
+func countSet(values ...string) int {
+	n := 0
+	for _, v := range values {
+		if v != "" {
+			n++
+		}
+	}
+	return n
+}
+
+func modelPath(config *accessServer.Config) string {
+	if *flagModel != "" {
+		return *flagModel
+	}
+	return config.Model
+}
+
+func policyFormat(path string) (string, error) {
+	if *flagFormat != "" {
+		return *flagFormat, nil
+	}
+	if path == "-" {
+		return policy.FormatCSV, nil
+	}
+	return policy.FormatFromPath(path)
+}
+
+func exportPolicies(path string) error {
+	format, err := policyFormat(path)
+	if err != nil {
+		return err
+	}
+	db, err := accessServer.OpenPolicyStore()
+	if err != nil {
+		return err
+	}
+	set, err := policy.Load(db)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return policy.Write(format, w, set)
+}
+
+func importPolicies(path string, config *accessServer.Config) error {
+	format, err := policyFormat(path)
+	if err != nil {
+		return err
+	}
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	desired, err := policy.Read(format, r)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	db, err := accessServer.OpenPolicyStore()
+	if err != nil {
+		return err
+	}
+	if err := accessServer.CheckRules(db, config, desired.Rules()); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	diff, err := policy.Import(db, desired, *flagReplace, *flagDryRun)
+	if err != nil {
+		return err
+	}
+	fmt.Print(diff)
+	summary := "applied"
+	switch {
+	case diff.Empty():
+		summary = "up to date"
+	case *flagDryRun:
+		summary = "dry run"
+	}
+	fmt.Printf("%d added, %d removed, %s\n", len(diff.Add), len(diff.Remove), summary)
+	return nil
+}
+
+// syncPolicies makes the store hold the policy files of dir, printing the plan
+// first. Rules missing from the files are only removed when an earlier sync
+// added them, unless forced.
+func syncPolicies(dir string, config *accessServer.Config) error {
+	desired, err := policy.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	db, err := accessServer.OpenPolicyStore()
+	if err != nil {
+		return err
+	}
+	if err := accessServer.CheckRules(db, config, desired.Rules()); err != nil {
+		return fmt.Errorf("%s: %v", dir, err)
+	}
+	plan, err := policy.Sync(db, desired, *flagForce, *flagDryRun)
+	if err != nil {
+		return err
+	}
+	fmt.Print(plan)
+	summary := "applied"
+	switch {
+	case plan.Empty():
+		summary = "up to date"
+	case *flagDryRun:
+		summary = "dry run"
+	}
+	fmt.Printf("%d added, %d removed, %d unmanaged kept, %s\n", len(plan.Add), len(plan.Remove), len(plan.Unmanaged), summary)
+	if len(plan.Unmanaged) > 0 {
+		fmt.Println("use -force to remove the unmanaged rules")
+	}
+	return nil
+}
+
+// testPolicies decides the test cases of path with the model and policy files
+// alone, printing an explanation of each failed case
+func testPolicies(path, modelPath, policyPath string) error {
+	if policyPath == "" {
+		return errors.New("-test needs -policy")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	cases, err := policy.ReadCases(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	text, err := policy.LoadModel(modelPath)
+	if err != nil {
+		return err
+	}
+	set, err := readPolicy(policyPath)
+	if err != nil {
+		return err
+	}
+	e, err := policy.NewEnforcer(text, set)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range policy.RunCases(e, cases) {
+		if result.Passed() {
+			continue
+		}
+		failed++
+		fmt.Print("FAIL ", result)
+	}
+	fmt.Printf("%d passed, %d failed\n", len(cases)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d cases failed", failed, len(cases))
+	}
+	return nil
+}
+
+// readPolicy reads a policy file, or every policy file of a directory
+func readPolicy(path string) (policy.Set, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return policy.ReadDir(path)
+	}
+	format, err := policyFormat(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	set, err := policy.Read(format, f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return set, nil
+}
+
+// lintPolicies reports the findings on the model and the policy files of
+// policyPath or, without, the rows of the store, failing on errors
+func lintPolicies(modelPath, policyPath string, config policy.LintConfig) error {
+	data, err := os.ReadFile(modelPath)
+	if err != nil {
+		return err
+	}
+	var rules []policy.Rule
+	if policyPath != "" {
+		set, err := readPolicy(policyPath)
+		if err != nil {
+			return err
+		}
+		rules = set.Rules()
+	} else {
+		db, err := accessServer.OpenPolicyStore()
+		if err != nil {
+			return err
+		}
+		if rules, err = policy.LoadRules(db); err != nil {
+			return err
+		}
+	}
+
+	findings := policy.Lint(string(data), rules, config)
+	errs := 0
+	for _, f := range findings {
+		if f.Severity == policy.SeverityError {
+			errs++
+		}
+	}
+	if *flagJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if findings == nil {
+			findings = []policy.Finding{}
+		}
+		if err := enc.Encode(findings); err != nil {
+			return err
+		}
+	} else {
+		for _, f := range findings {
+			fmt.Println(f)
+		}
+		fmt.Printf("%d errors, %d warnings\n", errs, len(findings)-errs)
+	}
+	if errs > 0 {
+		return fmt.Errorf("%s: %d lint errors", modelPath, errs)
+	}
+	return nil
+}
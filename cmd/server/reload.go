@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/100mslive/packages/log"
+	"github.com/fsnotify/fsnotify"
+	accessServer "github.com/piyush1104/access/pkg/server"
+	"github.com/spf13/viper"
+)
+
+// reloadDelay lets editors finish writing before the config is read
+const reloadDelay = 500 * time.Millisecond
+
+// watchConfig reloads the server section of the config file whenever the file
+// changes or the process receives SIGHUP, which also rotates certificates and
+// rereads the model without touching the config
+func watchConfig(server *accessServer.Server, stopped <-chan struct{}) {
+	path, kind := flagValue("config"), flagValue("type")
+	if path == "" {
+		log.Errorf("No config file, hot reload disabled")
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Errorf("Failed to watch config %v", err)
+		return
+	}
+	// watch the directory, editors and config maps replace the file
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		log.Errorf("Failed to watch config %v", err)
+		watcher.Close()
+		return
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer watcher.Close()
+		defer signal.Stop(hup)
+		var pending <-chan time.Time
+		for {
+			select {
+			case event := <-watcher.Events:
+				if filepath.Clean(event.Name) == filepath.Clean(path) && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					pending = time.After(reloadDelay)
+				}
+			case err := <-watcher.Errors:
+				log.Errorf("Config watch failed %v", err)
+			case <-hup:
+				pending = time.After(0)
+			case <-pending:
+				pending = nil
+				if err := reload(server, path, kind); err != nil {
+					log.Errorf("Config not reloaded %v", err)
+				}
+			case <-stopped:
+				return
+			}
+		}
+	}()
+}
+
+func reload(server *accessServer.Server, path, kind string) error {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if kind != "" {
+		v.SetConfigType(kind)
+	}
+	if err := v.ReadInConfig(); err != nil {
+		return err
+	}
+	config := accessServer.DefaultConfig()
+	if err := v.UnmarshalKey("server", config); err != nil {
+		return err
+	}
+	return server.Reload(config)
+}
+
+func flagValue(name string) string {
+	if f := flag.Lookup(name); f != nil {
+		return f.Value.String()
+	}
+	return ""
+}
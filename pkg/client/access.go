@@ -10,18 +10,27 @@ import (
 var (
 	// ErrClientNotConnected ...
 	ErrClientNotConnected = errors.New("Error: client not connected")
+	// ErrNotServing ...
+	ErrNotServing = errors.New("Error: server not serving")
 )
 
 func (client *Client) AuthorizeToken(ctx context.Context, token, resource, action string) (bool, error) {
+	return client.AuthorizeTypedToken(ctx, "", token, resource, action)
+}
+
+// AuthorizeTypedToken authorizes the holder of a token of a type configured on
+// the server, empty meaning a management token
+func (client *Client) AuthorizeTypedToken(ctx context.Context, tokenType, token, resource, action string) (bool, error) {
 	if !client.Connected() {
 		client.logger.Println(ErrClientNotConnected.Error())
 		return false, ErrClientNotConnected
 	}
 
 	reply, err := client.rpc.AuthorizeToken(ctx, &accesspb.AuthorizeTokenRequest{
-		Token:    token,
-		Resource: resource,
-		Action:   action,
+		Token:     token,
+		TokenType: tokenType,
+		Resource:  resource,
+		Action:    action,
 	})
 	if err != nil {
 		return false, err
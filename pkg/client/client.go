@@ -2,6 +2,9 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"log"
 	"os"
 	"sync/atomic"
@@ -11,9 +14,11 @@ import (
 	grpc_retry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	accesspb "github.com/piyush1104/access/pkg/internal"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
 const (
@@ -36,6 +41,8 @@ type Client struct {
 	connection     int32
 	metricsEnabled bool
 	retryEnabled   bool
+	tracingEnabled bool
+	credentials    *bearerCredentials
 	config         *Config
 	logger         *log.Logger
 	conn           *grpc.ClientConn
@@ -72,12 +79,16 @@ func (client *Client) Connect(ctx context.Context) error {
 	if client.config.Cert == "" {
 		options = append(options, grpc.WithInsecure())
 	} else {
-		creds, err := credentials.NewClientTLSFromFile(client.config.Cert, "")
+		tlsConfig, err := client.tlsConfig()
 		if err != nil {
 			client.logger.Print("Error!!!", err)
 			return err
 		}
-		options = append(options, grpc.WithTransportCredentials(creds))
+		options = append(options, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	}
+	if client.credentials != nil {
+		client.credentials.secure = client.config.Cert != ""
+		options = append(options, grpc.WithPerRPCCredentials(client.credentials))
 	}
 	var streamInterceptor []grpc.StreamClientInterceptor
 	var unaryInterceptor []grpc.UnaryClientInterceptor
@@ -86,6 +97,11 @@ func (client *Client) Connect(ctx context.Context) error {
 		options = append(options, grpc.WithUnaryInterceptor(grpc_prometheus.UnaryClientInterceptor))
 		options = append(options, grpc.WithStreamInterceptor(grpc_prometheus.StreamClientInterceptor))
 	}
+	if client.tracingEnabled {
+		// first in the chain so retries are traced as one call
+		streamInterceptor = append(streamInterceptor, otelgrpc.StreamClientInterceptor())
+		unaryInterceptor = append(unaryInterceptor, otelgrpc.UnaryClientInterceptor())
+	}
 	if client.retryEnabled {
 		opts := []grpc_retry.CallOption{
 			grpc_retry.WithBackoff(grpc_retry.BackoffLinear(100 * time.Millisecond)),
@@ -114,6 +130,51 @@ func (client *Client) Connect(ctx context.Context) error {
 	return nil
 }
 
+// tlsConfig trusts the Cert bundle and presents the client certificate, if any
+func (client *Client) tlsConfig() (*tls.Config, error) {
+	pem, err := os.ReadFile(client.config.Cert)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s: no certificates found", client.config.Cert)
+	}
+	tlsConfig := &tls.Config{
+		RootCAs:    pool,
+		ServerName: client.config.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if client.config.ClientCert != "" || client.config.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(client.config.ClientCert, client.config.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// bearerCredentials attaches a bearer token, and its type, to every call
+type bearerCredentials struct {
+	token     string
+	tokenType string
+	secure    bool
+}
+
+func (c *bearerCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	md := map[string]string{"authorization": "Bearer " + c.token}
+	if c.tokenType != "" {
+		md["x-access-token-type"] = c.tokenType
+	}
+	return md, nil
+}
+
+// RequireTransportSecurity holds once the client is configured for TLS
+func (c *bearerCredentials) RequireTransportSecurity() bool {
+	return c.secure
+}
+
 // Connected client connected status
 func (client *Client) Connected() bool {
 	return atomic.LoadInt32(&client.connection) == connected
@@ -127,10 +188,19 @@ func (client *Client) Close() error {
 	return nil
 }
 
-// Health ...
+// Health asks the server whether the access service is serving
 func (client *Client) Health(ctx context.Context) error {
 	if !client.Connected() {
 		return ErrClientNotConnected
 	}
+	reply, err := grpc_health_v1.NewHealthClient(client.conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{
+		Service: "access",
+	})
+	if err != nil {
+		return err
+	}
+	if reply.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		return ErrNotServing
+	}
 	return nil
 }
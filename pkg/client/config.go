@@ -2,8 +2,14 @@ package client
 
 // Config ...
 type Config struct {
-	Addr        string `mapstructure:"addr,omitempty"`
-	Cert        string `mapstructure:"cert,omitempty"`
+	Addr string `mapstructure:"addr,omitempty"`
+	// Cert is the CA bundle verifying the server certificate
+	Cert string `mapstructure:"cert,omitempty"`
+	// ClientCert and ClientKey identify the client to servers requiring mTLS
+	ClientCert string `mapstructure:"client_cert,omitempty"`
+	ClientKey  string `mapstructure:"client_key,omitempty"`
+	// ServerName overrides the name the server certificate is verified for
+	ServerName  string `mapstructure:"server_name,omitempty"`
 	DialTimeout int    `mapstructure:"dial_timeout,omitempty"`
 }
 
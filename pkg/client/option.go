@@ -1,5 +1,7 @@
 package client
 
+import "log"
+
 type Option func(c *Client)
 
 func WithMetrics() Option {
@@ -13,3 +15,26 @@ func WithRetry() Option {
 		c.retryEnabled = true
 	}
 }
+
+// WithTracing starts a span per call and propagates the trace to the server
+func WithTracing() Option {
+	return func(c *Client) {
+		c.tracingEnabled = true
+	}
+}
+
+// WithToken sends token as a bearer token on every call, authenticating the
+// client to servers protecting admin RPCs. tokenType is empty for management
+// tokens.
+func WithToken(token, tokenType string) Option {
+	return func(c *Client) {
+		c.credentials = &bearerCredentials{token: token, tokenType: tokenType}
+	}
+}
+
+// WithLogger replaces the logger printing connection progress and errors
+func WithLogger(logger *log.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
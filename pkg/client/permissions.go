@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+
+	accesspb "github.com/piyush1104/access/pkg/internal"
+)
+
+// Permission is an action allowed on a resource
+type Permission struct {
+	Resource string
+	Action   string
+}
+
+// PermissionQuery selects whose permissions to list. Exactly one of Subject
+// and Token must be set, TokenType being empty for management tokens;
+// PageToken continues from a previous page.
+type PermissionQuery struct {
+	Subject        string
+	Token          string
+	TokenType      string
+	ResourcePrefix string
+	PageSize       int
+	PageToken      string
+}
+
+// ListPermissions returns a page of permissions, after role expansion, and the
+// token of the next page, empty on the last one
+func (client *Client) ListPermissions(ctx context.Context, query PermissionQuery) ([]Permission, string, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return nil, "", ErrClientNotConnected
+	}
+
+	reply, err := client.rpc.ListPermissions(ctx, &accesspb.ListPermissionsRequest{
+		Subject:        query.Subject,
+		Token:          query.Token,
+		TokenType:      query.TokenType,
+		ResourcePrefix: query.ResourcePrefix,
+		PageSize:       int32(query.PageSize),
+		PageToken:      query.PageToken,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	permissions := make([]Permission, 0, len(reply.Permissions))
+	for _, p := range reply.Permissions {
+		permissions = append(permissions, Permission{
+			Resource: p.GetResource(),
+			Action:   p.GetAction(),
+		})
+	}
+	return permissions, reply.NextPageToken, nil
+}
+
+// SubjectGrant is a subject allowed an action on a resource. Path runs from
+// the subject through its roles to the subject named in the policy.
+type SubjectGrant struct {
+	Subject string
+	Path    []string
+	Role    bool
+}
+
+// ListSubjects returns the subjects allowed action on resource, directly or
+// through roles
+func (client *Client) ListSubjects(ctx context.Context, resource, action string) ([]SubjectGrant, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return nil, ErrClientNotConnected
+	}
+
+	reply, err := client.rpc.ListSubjects(ctx, &accesspb.ListSubjectsRequest{
+		Resource: resource,
+		Action:   action,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	subjects := make([]SubjectGrant, 0, len(reply.Subjects))
+	for _, s := range reply.Subjects {
+		subjects = append(subjects, SubjectGrant{
+			Subject: s.GetSubject(),
+			Path:    s.GetPath(),
+			Role:    s.GetRole(),
+		})
+	}
+	return subjects, nil
+}
@@ -0,0 +1,375 @@
+package client
+
+import (
+	"context"
+
+	accesspb "github.com/piyush1104/access/pkg/internal"
+)
+
+// Policy allows a subject an action on a resource
+type Policy struct {
+	Subject  string `json:"subject"`
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+// RoleAssignment makes a subject a member of a role
+type RoleAssignment struct {
+	Subject string `json:"subject"`
+	Role    string `json:"role"`
+}
+
+// ListPolicies returns the policies matching filter, empty fields matching any
+// value
+func (client *Client) ListPolicies(ctx context.Context, filter Policy) ([]Policy, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return nil, ErrClientNotConnected
+	}
+
+	reply, err := client.rpc.ListPolicies(ctx, &accesspb.ListPoliciesRequest{
+		Subject:  filter.Subject,
+		Resource: filter.Resource,
+		Action:   filter.Action,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	policies := make([]Policy, 0, len(reply.Policies))
+	for _, p := range reply.Policies {
+		policies = append(policies, Policy{
+			Subject:  p.GetSubject(),
+			Resource: p.GetResource(),
+			Action:   p.GetAction(),
+		})
+	}
+	return policies, nil
+}
+
+// AddPolicy adds a policy, reporting whether it was new
+func (client *Client) AddPolicy(ctx context.Context, policy Policy) (bool, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return false, ErrClientNotConnected
+	}
+
+	reply, err := client.rpc.AddPolicy(ctx, &accesspb.PolicyRequest{Policy: policy.proto()})
+	if err != nil {
+		return false, err
+	}
+	return reply.Changed, nil
+}
+
+// RemovePolicy removes a policy, reporting whether it existed
+func (client *Client) RemovePolicy(ctx context.Context, policy Policy) (bool, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return false, ErrClientNotConnected
+	}
+
+	reply, err := client.rpc.RemovePolicy(ctx, &accesspb.PolicyRequest{Policy: policy.proto()})
+	if err != nil {
+		return false, err
+	}
+	return reply.Changed, nil
+}
+
+func (p Policy) proto() *accesspb.Policy {
+	return &accesspb.Policy{
+		Subject:  p.Subject,
+		Resource: p.Resource,
+		Action:   p.Action,
+	}
+}
+
+// ListRoles returns the role assignments matching filter, empty fields
+// matching any value
+func (client *Client) ListRoles(ctx context.Context, filter RoleAssignment) ([]RoleAssignment, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return nil, ErrClientNotConnected
+	}
+
+	reply, err := client.rpc.ListRoles(ctx, &accesspb.ListRolesRequest{
+		Subject: filter.Subject,
+		Role:    filter.Role,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]RoleAssignment, 0, len(reply.Roles))
+	for _, r := range reply.Roles {
+		roles = append(roles, RoleAssignment{
+			Subject: r.GetSubject(),
+			Role:    r.GetRole(),
+		})
+	}
+	return roles, nil
+}
+
+// AssignRole assigns a role, reporting whether it was new
+func (client *Client) AssignRole(ctx context.Context, role RoleAssignment) (bool, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return false, ErrClientNotConnected
+	}
+
+	reply, err := client.rpc.AssignRole(ctx, &accesspb.RoleRequest{Role: role.proto()})
+	if err != nil {
+		return false, err
+	}
+	return reply.Changed, nil
+}
+
+// UnassignRole removes a role assignment, reporting whether it existed
+func (client *Client) UnassignRole(ctx context.Context, role RoleAssignment) (bool, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return false, ErrClientNotConnected
+	}
+
+	reply, err := client.rpc.UnassignRole(ctx, &accesspb.RoleRequest{Role: role.proto()})
+	if err != nil {
+		return false, err
+	}
+	return reply.Changed, nil
+}
+
+func (r RoleAssignment) proto() *accesspb.RoleAssignment {
+	return &accesspb.RoleAssignment{
+		Subject: r.Subject,
+		Role:    r.Role,
+	}
+}
+
+// PolicyRule is a rule of any policy type, as in a casbin CSV line
+type PolicyRule struct {
+	PType  string   `json:"ptype"`
+	Values []string `json:"values"`
+}
+
+// PolicyImport is a complete policy to import in Format, csv, json or yaml.
+// Replace removes the rules it does not have instead of merging, DryRun only
+// reports the changes.
+type PolicyImport struct {
+	Format  string
+	Data    []byte
+	Replace bool
+	DryRun  bool
+}
+
+// ImportResult lists the rules an import added and removed, or would have on
+// a dry run
+type ImportResult struct {
+	Added   []PolicyRule `json:"added"`
+	Removed []PolicyRule `json:"removed"`
+	Applied bool         `json:"applied"`
+}
+
+// ExportPolicies returns the complete policy in format, csv, json or yaml
+func (client *Client) ExportPolicies(ctx context.Context, format string) ([]byte, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return nil, ErrClientNotConnected
+	}
+
+	reply, err := client.rpc.ExportPolicies(ctx, &accesspb.ExportPoliciesRequest{Format: format})
+	if err != nil {
+		return nil, err
+	}
+	return reply.Data, nil
+}
+
+// ImportPolicies imports a complete policy
+func (client *Client) ImportPolicies(ctx context.Context, in PolicyImport) (*ImportResult, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return nil, ErrClientNotConnected
+	}
+
+	mode := accesspb.ImportMode_IMPORT_MODE_MERGE
+	if in.Replace {
+		mode = accesspb.ImportMode_IMPORT_MODE_REPLACE
+	}
+	reply, err := client.rpc.ImportPolicies(ctx, &accesspb.ImportPoliciesRequest{
+		Format: in.Format,
+		Data:   in.Data,
+		Mode:   mode,
+		DryRun: in.DryRun,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ImportResult{
+		Added:   policyRules(reply.Added),
+		Removed: policyRules(reply.Removed),
+		Applied: reply.Applied,
+	}, nil
+}
+
+func policyRules(rules []*accesspb.PolicyRule) []PolicyRule {
+	out := make([]PolicyRule, 0, len(rules))
+	for _, r := range rules {
+		out = append(out, PolicyRule{
+			PType:  r.GetPType(),
+			Values: r.GetValues(),
+		})
+	}
+	return out
+}
+
+// Probe is a decision to evaluate, whether subject may take action on resource
+type Probe struct {
+	Subject  string `json:"subject"`
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+// PolicyChange is a proposed change to the policy, the rules to add and to
+// remove
+type PolicyChange struct {
+	Add    []PolicyRule
+	Remove []PolicyRule
+}
+
+// DecisionChange is a decision a change flips
+type DecisionChange struct {
+	Probe
+	Before bool `json:"before"`
+	After  bool `json:"after"`
+}
+
+// WhatIfResult lists the decisions a change flips out of the ones probed
+type WhatIfResult struct {
+	Changes []DecisionChange `json:"changes"`
+	Probed  int              `json:"probed"`
+}
+
+// WhatIf evaluates which decisions change would flip without applying it,
+// among probes or, with none, the decisions of the subjects and resources the
+// change touches
+func (client *Client) WhatIf(ctx context.Context, change PolicyChange, probes []Probe) (*WhatIfResult, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return nil, ErrClientNotConnected
+	}
+
+	req := &accesspb.WhatIfRequest{
+		Add:    policyRulesProto(change.Add),
+		Remove: policyRulesProto(change.Remove),
+	}
+	for _, p := range probes {
+		req.Probes = append(req.Probes, &accesspb.Probe{
+			Subject:  p.Subject,
+			Resource: p.Resource,
+			Action:   p.Action,
+		})
+	}
+	reply, err := client.rpc.WhatIf(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &WhatIfResult{
+		Changes: make([]DecisionChange, 0, len(reply.Changes)),
+		Probed:  int(reply.Probed),
+	}
+	for _, c := range reply.Changes {
+		result.Changes = append(result.Changes, DecisionChange{
+			Probe: Probe{
+				Subject:  c.GetSubject(),
+				Resource: c.GetResource(),
+				Action:   c.GetAction(),
+			},
+			Before: c.GetBefore(),
+			After:  c.GetAfter(),
+		})
+	}
+	return result, nil
+}
+
+func policyRulesProto(rules []PolicyRule) []*accesspb.PolicyRule {
+	out := make([]*accesspb.PolicyRule, 0, len(rules))
+	for _, r := range rules {
+		out = append(out, &accesspb.PolicyRule{
+			PType:  r.PType,
+			Values: r.Values,
+		})
+	}
+	return out
+}
+
+// RuleError is why the rule at Index of a bulk request is invalid
+type RuleError struct {
+	Index   int    `json:"index"`
+	Message string `json:"message"`
+}
+
+// BulkResult lists the rules a bulk request added and removed or, when any
+// rule is invalid and nothing changed, the errors
+type BulkResult struct {
+	Added   []PolicyRule `json:"added"`
+	Removed []PolicyRule `json:"removed"`
+	Errors  []RuleError  `json:"errors,omitempty"`
+	Applied bool         `json:"applied"`
+}
+
+// AddPolicies adds p rules and g links in one transaction, all or none
+func (client *Client) AddPolicies(ctx context.Context, rules []PolicyRule) (*BulkResult, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return nil, ErrClientNotConnected
+	}
+
+	reply, err := client.rpc.AddPolicies(ctx, &accesspb.BulkPolicyRequest{Rules: policyRulesProto(rules)})
+	if err != nil {
+		return nil, err
+	}
+	return bulkResult(reply), nil
+}
+
+// RemovePolicies removes p rules and g links in one transaction, all or none
+func (client *Client) RemovePolicies(ctx context.Context, rules []PolicyRule) (*BulkResult, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return nil, ErrClientNotConnected
+	}
+
+	reply, err := client.rpc.RemovePolicies(ctx, &accesspb.BulkPolicyRequest{Rules: policyRulesProto(rules)})
+	if err != nil {
+		return nil, err
+	}
+	return bulkResult(reply), nil
+}
+
+// ReplacePolicies makes rules the complete p rules and g links of subjects in
+// one transaction, every rule being of one of them
+func (client *Client) ReplacePolicies(ctx context.Context, subjects []string, rules []PolicyRule) (*BulkResult, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return nil, ErrClientNotConnected
+	}
+
+	reply, err := client.rpc.ReplacePolicies(ctx, &accesspb.ReplacePoliciesRequest{
+		Subjects: subjects,
+		Rules:    policyRulesProto(rules),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bulkResult(reply), nil
+}
+
+func bulkResult(reply *accesspb.BulkPolicyReply) *BulkResult {
+	result := &BulkResult{
+		Added:   policyRules(reply.Added),
+		Removed: policyRules(reply.Removed),
+		Applied: reply.Applied,
+	}
+	for _, e := range reply.Errors {
+		result.Errors = append(result.Errors, RuleError{Index: int(e.GetIndex()), Message: e.GetMessage()})
+	}
+	return result
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+
+	accesspb "github.com/piyush1104/access/pkg/internal"
+)
+
+// ResourceType is a kind of resource, the resources starting with Prefix, and
+// the actions allowed on it. Static types come from the server config and
+// cannot be changed through the client.
+type ResourceType struct {
+	Name    string   `json:"name"`
+	Prefix  string   `json:"prefix"`
+	Actions []string `json:"actions"`
+	Static  bool     `json:"static"`
+}
+
+// ListResourceTypes returns the registered resource types
+func (client *Client) ListResourceTypes(ctx context.Context) ([]ResourceType, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return nil, ErrClientNotConnected
+	}
+
+	reply, err := client.rpc.ListResourceTypes(ctx, &accesspb.ListResourceTypesRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]ResourceType, 0, len(reply.Types))
+	for _, t := range reply.Types {
+		types = append(types, ResourceType{
+			Name:    t.GetName(),
+			Prefix:  t.GetPrefix(),
+			Actions: t.GetActions(),
+			Static:  t.GetStatic(),
+		})
+	}
+	return types, nil
+}
+
+// PutResourceType adds a resource type or replaces the one of the same name,
+// reporting whether it changed
+func (client *Client) PutResourceType(ctx context.Context, t ResourceType) (bool, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return false, ErrClientNotConnected
+	}
+
+	reply, err := client.rpc.PutResourceType(ctx, &accesspb.ResourceTypeRequest{Type: &accesspb.ResourceType{
+		Name:    t.Name,
+		Prefix:  t.Prefix,
+		Actions: t.Actions,
+	}})
+	if err != nil {
+		return false, err
+	}
+	return reply.Changed, nil
+}
+
+// DeleteResourceType removes a resource type, reporting whether it existed
+func (client *Client) DeleteResourceType(ctx context.Context, name string) (bool, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return false, ErrClientNotConnected
+	}
+
+	reply, err := client.rpc.DeleteResourceType(ctx, &accesspb.DeleteResourceTypeRequest{Name: name})
+	if err != nil {
+		return false, err
+	}
+	return reply.Changed, nil
+}
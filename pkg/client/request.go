@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	accesspb "github.com/piyush1104/access/pkg/internal"
+)
+
+// AccessRequestStatus ...
+type AccessRequestStatus int32
+
+const (
+	// AccessRequestAny matches every status when listing requests
+	AccessRequestAny      = AccessRequestStatus(accesspb.AccessRequestStatus_ACCESS_REQUEST_STATUS_UNSPECIFIED)
+	AccessRequestPending  = AccessRequestStatus(accesspb.AccessRequestStatus_ACCESS_REQUEST_STATUS_PENDING)
+	AccessRequestApproved = AccessRequestStatus(accesspb.AccessRequestStatus_ACCESS_REQUEST_STATUS_APPROVED)
+	AccessRequestRejected = AccessRequestStatus(accesspb.AccessRequestStatus_ACCESS_REQUEST_STATUS_REJECTED)
+	AccessRequestExpired  = AccessRequestStatus(accesspb.AccessRequestStatus_ACCESS_REQUEST_STATUS_EXPIRED)
+)
+
+func (s AccessRequestStatus) String() string {
+	switch s {
+	case AccessRequestPending:
+		return "pending"
+	case AccessRequestApproved:
+		return "approved"
+	case AccessRequestRejected:
+		return "rejected"
+	case AccessRequestExpired:
+		return "expired"
+	}
+	return "unknown"
+}
+
+// AccessRequest is a just-in-time request for a time-limited grant
+type AccessRequest struct {
+	ID        uint64
+	Subject   string
+	Resource  string
+	Action    string
+	Reason    string
+	Duration  time.Duration
+	Status    AccessRequestStatus
+	Approver  string
+	Comment   string
+	CreatedAt time.Time
+	DecidedAt time.Time
+	ExpiresAt time.Time
+}
+
+func newAccessRequest(r *accesspb.AccessRequest) *AccessRequest {
+	return &AccessRequest{
+		ID:        r.GetID(),
+		Subject:   r.GetSubject(),
+		Resource:  r.GetResource(),
+		Action:    r.GetAction(),
+		Reason:    r.GetReason(),
+		Duration:  time.Duration(r.GetDuration()) * time.Second,
+		Status:    AccessRequestStatus(r.GetStatus()),
+		Approver:  r.GetApprover(),
+		Comment:   r.GetComment(),
+		CreatedAt: unixTime(r.GetCreatedAt()),
+		DecidedAt: unixTime(r.GetDecidedAt()),
+		ExpiresAt: unixTime(r.GetExpiresAt()),
+	}
+}
+
+func unixTime(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// RequestAccess asks for action on resource for the given duration, zero
+// meaning the server default
+func (client *Client) RequestAccess(ctx context.Context, subject, resource, action, reason string, duration time.Duration) (*AccessRequest, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return nil, ErrClientNotConnected
+	}
+
+	reply, err := client.rpc.RequestAccess(ctx, &accesspb.RequestAccessRequest{
+		Subject:  subject,
+		Resource: resource,
+		Action:   action,
+		Reason:   reason,
+		Duration: int64(duration / time.Second),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newAccessRequest(reply.Request), nil
+}
+
+// ApproveAccess approves as the identity the client authenticates with, its
+// token or certificate. A non-empty approver picks which of its subjects.
+func (client *Client) ApproveAccess(ctx context.Context, id uint64, approver, comment string) (*AccessRequest, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return nil, ErrClientNotConnected
+	}
+
+	reply, err := client.rpc.ApproveAccess(ctx, &accesspb.ApproveAccessRequest{
+		ID:       id,
+		Approver: approver,
+		Comment:  comment,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newAccessRequest(reply.Request), nil
+}
+
+// RejectAccess rejects as the identity the client authenticates with, its
+// token or certificate. A non-empty approver picks which of its subjects.
+func (client *Client) RejectAccess(ctx context.Context, id uint64, approver, comment string) (*AccessRequest, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return nil, ErrClientNotConnected
+	}
+
+	reply, err := client.rpc.RejectAccess(ctx, &accesspb.RejectAccessRequest{
+		ID:       id,
+		Approver: approver,
+		Comment:  comment,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newAccessRequest(reply.Request), nil
+}
+
+// ListAccessRequests lists requests, newest first. An empty subject or
+// AccessRequestAny leaves that filter out.
+func (client *Client) ListAccessRequests(ctx context.Context, subject string, status AccessRequestStatus) ([]*AccessRequest, error) {
+	if !client.Connected() {
+		client.logger.Println(ErrClientNotConnected.Error())
+		return nil, ErrClientNotConnected
+	}
+
+	reply, err := client.rpc.ListAccessRequests(ctx, &accesspb.ListAccessRequestsRequest{
+		Subject: subject,
+		Status:  accesspb.AccessRequestStatus(status),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	requests := make([]*AccessRequest, 0, len(reply.Requests))
+	for _, r := range reply.Requests {
+		requests = append(requests, newAccessRequest(r))
+	}
+	return requests, nil
+}
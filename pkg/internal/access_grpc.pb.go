@@ -24,6 +24,27 @@ const _ = grpc.SupportPackageIsVersion7
 type AccessClient interface {
 	AuthorizeToken(ctx context.Context, in *AuthorizeTokenRequest, opts ...grpc.CallOption) (*AuthorizeReply, error)
 	Authorize(ctx context.Context, in *AuthorizeRequest, opts ...grpc.CallOption) (*AuthorizeReply, error)
+	RequestAccess(ctx context.Context, in *RequestAccessRequest, opts ...grpc.CallOption) (*AccessRequestReply, error)
+	ApproveAccess(ctx context.Context, in *ApproveAccessRequest, opts ...grpc.CallOption) (*AccessRequestReply, error)
+	RejectAccess(ctx context.Context, in *RejectAccessRequest, opts ...grpc.CallOption) (*AccessRequestReply, error)
+	ListAccessRequests(ctx context.Context, in *ListAccessRequestsRequest, opts ...grpc.CallOption) (*ListAccessRequestsReply, error)
+	ListPermissions(ctx context.Context, in *ListPermissionsRequest, opts ...grpc.CallOption) (*ListPermissionsReply, error)
+	ListSubjects(ctx context.Context, in *ListSubjectsRequest, opts ...grpc.CallOption) (*ListSubjectsReply, error)
+	ListPolicies(ctx context.Context, in *ListPoliciesRequest, opts ...grpc.CallOption) (*ListPoliciesReply, error)
+	AddPolicy(ctx context.Context, in *PolicyRequest, opts ...grpc.CallOption) (*ChangeReply, error)
+	RemovePolicy(ctx context.Context, in *PolicyRequest, opts ...grpc.CallOption) (*ChangeReply, error)
+	ListRoles(ctx context.Context, in *ListRolesRequest, opts ...grpc.CallOption) (*ListRolesReply, error)
+	AssignRole(ctx context.Context, in *RoleRequest, opts ...grpc.CallOption) (*ChangeReply, error)
+	UnassignRole(ctx context.Context, in *RoleRequest, opts ...grpc.CallOption) (*ChangeReply, error)
+	ExportPolicies(ctx context.Context, in *ExportPoliciesRequest, opts ...grpc.CallOption) (*ExportPoliciesReply, error)
+	ImportPolicies(ctx context.Context, in *ImportPoliciesRequest, opts ...grpc.CallOption) (*ImportPoliciesReply, error)
+	WhatIf(ctx context.Context, in *WhatIfRequest, opts ...grpc.CallOption) (*WhatIfReply, error)
+	ListResourceTypes(ctx context.Context, in *ListResourceTypesRequest, opts ...grpc.CallOption) (*ListResourceTypesReply, error)
+	PutResourceType(ctx context.Context, in *ResourceTypeRequest, opts ...grpc.CallOption) (*ChangeReply, error)
+	DeleteResourceType(ctx context.Context, in *DeleteResourceTypeRequest, opts ...grpc.CallOption) (*ChangeReply, error)
+	AddPolicies(ctx context.Context, in *BulkPolicyRequest, opts ...grpc.CallOption) (*BulkPolicyReply, error)
+	RemovePolicies(ctx context.Context, in *BulkPolicyRequest, opts ...grpc.CallOption) (*BulkPolicyReply, error)
+	ReplacePolicies(ctx context.Context, in *ReplacePoliciesRequest, opts ...grpc.CallOption) (*BulkPolicyReply, error)
 }
 
 type accessClient struct {
@@ -52,12 +73,222 @@ func (c *accessClient) Authorize(ctx context.Context, in *AuthorizeRequest, opts
 	return out, nil
 }
 
+func (c *accessClient) RequestAccess(ctx context.Context, in *RequestAccessRequest, opts ...grpc.CallOption) (*AccessRequestReply, error) {
+	out := new(AccessRequestReply)
+	err := c.cc.Invoke(ctx, "/access.Access/RequestAccess", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) ApproveAccess(ctx context.Context, in *ApproveAccessRequest, opts ...grpc.CallOption) (*AccessRequestReply, error) {
+	out := new(AccessRequestReply)
+	err := c.cc.Invoke(ctx, "/access.Access/ApproveAccess", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) RejectAccess(ctx context.Context, in *RejectAccessRequest, opts ...grpc.CallOption) (*AccessRequestReply, error) {
+	out := new(AccessRequestReply)
+	err := c.cc.Invoke(ctx, "/access.Access/RejectAccess", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) ListAccessRequests(ctx context.Context, in *ListAccessRequestsRequest, opts ...grpc.CallOption) (*ListAccessRequestsReply, error) {
+	out := new(ListAccessRequestsReply)
+	err := c.cc.Invoke(ctx, "/access.Access/ListAccessRequests", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) ListPermissions(ctx context.Context, in *ListPermissionsRequest, opts ...grpc.CallOption) (*ListPermissionsReply, error) {
+	out := new(ListPermissionsReply)
+	err := c.cc.Invoke(ctx, "/access.Access/ListPermissions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) ListSubjects(ctx context.Context, in *ListSubjectsRequest, opts ...grpc.CallOption) (*ListSubjectsReply, error) {
+	out := new(ListSubjectsReply)
+	err := c.cc.Invoke(ctx, "/access.Access/ListSubjects", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) ListPolicies(ctx context.Context, in *ListPoliciesRequest, opts ...grpc.CallOption) (*ListPoliciesReply, error) {
+	out := new(ListPoliciesReply)
+	err := c.cc.Invoke(ctx, "/access.Access/ListPolicies", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) AddPolicy(ctx context.Context, in *PolicyRequest, opts ...grpc.CallOption) (*ChangeReply, error) {
+	out := new(ChangeReply)
+	err := c.cc.Invoke(ctx, "/access.Access/AddPolicy", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) RemovePolicy(ctx context.Context, in *PolicyRequest, opts ...grpc.CallOption) (*ChangeReply, error) {
+	out := new(ChangeReply)
+	err := c.cc.Invoke(ctx, "/access.Access/RemovePolicy", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) ListRoles(ctx context.Context, in *ListRolesRequest, opts ...grpc.CallOption) (*ListRolesReply, error) {
+	out := new(ListRolesReply)
+	err := c.cc.Invoke(ctx, "/access.Access/ListRoles", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) AssignRole(ctx context.Context, in *RoleRequest, opts ...grpc.CallOption) (*ChangeReply, error) {
+	out := new(ChangeReply)
+	err := c.cc.Invoke(ctx, "/access.Access/AssignRole", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) UnassignRole(ctx context.Context, in *RoleRequest, opts ...grpc.CallOption) (*ChangeReply, error) {
+	out := new(ChangeReply)
+	err := c.cc.Invoke(ctx, "/access.Access/UnassignRole", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) ExportPolicies(ctx context.Context, in *ExportPoliciesRequest, opts ...grpc.CallOption) (*ExportPoliciesReply, error) {
+	out := new(ExportPoliciesReply)
+	err := c.cc.Invoke(ctx, "/access.Access/ExportPolicies", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) ImportPolicies(ctx context.Context, in *ImportPoliciesRequest, opts ...grpc.CallOption) (*ImportPoliciesReply, error) {
+	out := new(ImportPoliciesReply)
+	err := c.cc.Invoke(ctx, "/access.Access/ImportPolicies", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) WhatIf(ctx context.Context, in *WhatIfRequest, opts ...grpc.CallOption) (*WhatIfReply, error) {
+	out := new(WhatIfReply)
+	err := c.cc.Invoke(ctx, "/access.Access/WhatIf", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) ListResourceTypes(ctx context.Context, in *ListResourceTypesRequest, opts ...grpc.CallOption) (*ListResourceTypesReply, error) {
+	out := new(ListResourceTypesReply)
+	err := c.cc.Invoke(ctx, "/access.Access/ListResourceTypes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) PutResourceType(ctx context.Context, in *ResourceTypeRequest, opts ...grpc.CallOption) (*ChangeReply, error) {
+	out := new(ChangeReply)
+	err := c.cc.Invoke(ctx, "/access.Access/PutResourceType", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) DeleteResourceType(ctx context.Context, in *DeleteResourceTypeRequest, opts ...grpc.CallOption) (*ChangeReply, error) {
+	out := new(ChangeReply)
+	err := c.cc.Invoke(ctx, "/access.Access/DeleteResourceType", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) AddPolicies(ctx context.Context, in *BulkPolicyRequest, opts ...grpc.CallOption) (*BulkPolicyReply, error) {
+	out := new(BulkPolicyReply)
+	err := c.cc.Invoke(ctx, "/access.Access/AddPolicies", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) RemovePolicies(ctx context.Context, in *BulkPolicyRequest, opts ...grpc.CallOption) (*BulkPolicyReply, error) {
+	out := new(BulkPolicyReply)
+	err := c.cc.Invoke(ctx, "/access.Access/RemovePolicies", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessClient) ReplacePolicies(ctx context.Context, in *ReplacePoliciesRequest, opts ...grpc.CallOption) (*BulkPolicyReply, error) {
+	out := new(BulkPolicyReply)
+	err := c.cc.Invoke(ctx, "/access.Access/ReplacePolicies", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AccessServer is the server API for Access service.
 // All implementations must embed UnimplementedAccessServer
 // for forward compatibility
 type AccessServer interface {
 	AuthorizeToken(context.Context, *AuthorizeTokenRequest) (*AuthorizeReply, error)
 	Authorize(context.Context, *AuthorizeRequest) (*AuthorizeReply, error)
+	RequestAccess(context.Context, *RequestAccessRequest) (*AccessRequestReply, error)
+	ApproveAccess(context.Context, *ApproveAccessRequest) (*AccessRequestReply, error)
+	RejectAccess(context.Context, *RejectAccessRequest) (*AccessRequestReply, error)
+	ListAccessRequests(context.Context, *ListAccessRequestsRequest) (*ListAccessRequestsReply, error)
+	ListPermissions(context.Context, *ListPermissionsRequest) (*ListPermissionsReply, error)
+	ListSubjects(context.Context, *ListSubjectsRequest) (*ListSubjectsReply, error)
+	ListPolicies(context.Context, *ListPoliciesRequest) (*ListPoliciesReply, error)
+	AddPolicy(context.Context, *PolicyRequest) (*ChangeReply, error)
+	RemovePolicy(context.Context, *PolicyRequest) (*ChangeReply, error)
+	ListRoles(context.Context, *ListRolesRequest) (*ListRolesReply, error)
+	AssignRole(context.Context, *RoleRequest) (*ChangeReply, error)
+	UnassignRole(context.Context, *RoleRequest) (*ChangeReply, error)
+	ExportPolicies(context.Context, *ExportPoliciesRequest) (*ExportPoliciesReply, error)
+	ImportPolicies(context.Context, *ImportPoliciesRequest) (*ImportPoliciesReply, error)
+	WhatIf(context.Context, *WhatIfRequest) (*WhatIfReply, error)
+	ListResourceTypes(context.Context, *ListResourceTypesRequest) (*ListResourceTypesReply, error)
+	PutResourceType(context.Context, *ResourceTypeRequest) (*ChangeReply, error)
+	DeleteResourceType(context.Context, *DeleteResourceTypeRequest) (*ChangeReply, error)
+	AddPolicies(context.Context, *BulkPolicyRequest) (*BulkPolicyReply, error)
+	RemovePolicies(context.Context, *BulkPolicyRequest) (*BulkPolicyReply, error)
+	ReplacePolicies(context.Context, *ReplacePoliciesRequest) (*BulkPolicyReply, error)
 	mustEmbedUnimplementedAccessServer()
 }
 
@@ -71,6 +302,69 @@ func (UnimplementedAccessServer) AuthorizeToken(context.Context, *AuthorizeToken
 func (UnimplementedAccessServer) Authorize(context.Context, *AuthorizeRequest) (*AuthorizeReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Authorize not implemented")
 }
+func (UnimplementedAccessServer) RequestAccess(context.Context, *RequestAccessRequest) (*AccessRequestReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestAccess not implemented")
+}
+func (UnimplementedAccessServer) ApproveAccess(context.Context, *ApproveAccessRequest) (*AccessRequestReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApproveAccess not implemented")
+}
+func (UnimplementedAccessServer) RejectAccess(context.Context, *RejectAccessRequest) (*AccessRequestReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RejectAccess not implemented")
+}
+func (UnimplementedAccessServer) ListAccessRequests(context.Context, *ListAccessRequestsRequest) (*ListAccessRequestsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAccessRequests not implemented")
+}
+func (UnimplementedAccessServer) ListPermissions(context.Context, *ListPermissionsRequest) (*ListPermissionsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPermissions not implemented")
+}
+func (UnimplementedAccessServer) ListSubjects(context.Context, *ListSubjectsRequest) (*ListSubjectsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSubjects not implemented")
+}
+func (UnimplementedAccessServer) ListPolicies(context.Context, *ListPoliciesRequest) (*ListPoliciesReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPolicies not implemented")
+}
+func (UnimplementedAccessServer) AddPolicy(context.Context, *PolicyRequest) (*ChangeReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddPolicy not implemented")
+}
+func (UnimplementedAccessServer) RemovePolicy(context.Context, *PolicyRequest) (*ChangeReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemovePolicy not implemented")
+}
+func (UnimplementedAccessServer) ListRoles(context.Context, *ListRolesRequest) (*ListRolesReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRoles not implemented")
+}
+func (UnimplementedAccessServer) AssignRole(context.Context, *RoleRequest) (*ChangeReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AssignRole not implemented")
+}
+func (UnimplementedAccessServer) UnassignRole(context.Context, *RoleRequest) (*ChangeReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnassignRole not implemented")
+}
+func (UnimplementedAccessServer) ExportPolicies(context.Context, *ExportPoliciesRequest) (*ExportPoliciesReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExportPolicies not implemented")
+}
+func (UnimplementedAccessServer) ImportPolicies(context.Context, *ImportPoliciesRequest) (*ImportPoliciesReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportPolicies not implemented")
+}
+func (UnimplementedAccessServer) WhatIf(context.Context, *WhatIfRequest) (*WhatIfReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WhatIf not implemented")
+}
+func (UnimplementedAccessServer) ListResourceTypes(context.Context, *ListResourceTypesRequest) (*ListResourceTypesReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListResourceTypes not implemented")
+}
+func (UnimplementedAccessServer) PutResourceType(context.Context, *ResourceTypeRequest) (*ChangeReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PutResourceType not implemented")
+}
+func (UnimplementedAccessServer) DeleteResourceType(context.Context, *DeleteResourceTypeRequest) (*ChangeReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteResourceType not implemented")
+}
+func (UnimplementedAccessServer) AddPolicies(context.Context, *BulkPolicyRequest) (*BulkPolicyReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddPolicies not implemented")
+}
+func (UnimplementedAccessServer) RemovePolicies(context.Context, *BulkPolicyRequest) (*BulkPolicyReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemovePolicies not implemented")
+}
+func (UnimplementedAccessServer) ReplacePolicies(context.Context, *ReplacePoliciesRequest) (*BulkPolicyReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplacePolicies not implemented")
+}
 func (UnimplementedAccessServer) mustEmbedUnimplementedAccessServer() {}
 
 // UnsafeAccessServer may be embedded to opt out of forward compatibility for this service.
@@ -120,6 +414,384 @@ func _Access_Authorize_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Access_RequestAccess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestAccessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).RequestAccess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/RequestAccess",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).RequestAccess(ctx, req.(*RequestAccessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_ApproveAccess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApproveAccessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).ApproveAccess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/ApproveAccess",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).ApproveAccess(ctx, req.(*ApproveAccessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_RejectAccess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RejectAccessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).RejectAccess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/RejectAccess",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).RejectAccess(ctx, req.(*RejectAccessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_ListAccessRequests_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAccessRequestsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).ListAccessRequests(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/ListAccessRequests",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).ListAccessRequests(ctx, req.(*ListAccessRequestsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_ListPermissions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPermissionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).ListPermissions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/ListPermissions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).ListPermissions(ctx, req.(*ListPermissionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_ListSubjects_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSubjectsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).ListSubjects(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/ListSubjects",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).ListSubjects(ctx, req.(*ListSubjectsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_ListPolicies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPoliciesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).ListPolicies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/ListPolicies",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).ListPolicies(ctx, req.(*ListPoliciesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_AddPolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).AddPolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/AddPolicy",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).AddPolicy(ctx, req.(*PolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_RemovePolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).RemovePolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/RemovePolicy",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).RemovePolicy(ctx, req.(*PolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_ListRoles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRolesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).ListRoles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/ListRoles",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).ListRoles(ctx, req.(*ListRolesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_AssignRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).AssignRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/AssignRole",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).AssignRole(ctx, req.(*RoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_UnassignRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).UnassignRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/UnassignRole",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).UnassignRole(ctx, req.(*RoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_ExportPolicies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportPoliciesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).ExportPolicies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/ExportPolicies",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).ExportPolicies(ctx, req.(*ExportPoliciesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_ImportPolicies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportPoliciesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).ImportPolicies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/ImportPolicies",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).ImportPolicies(ctx, req.(*ImportPoliciesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_WhatIf_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WhatIfRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).WhatIf(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/WhatIf",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).WhatIf(ctx, req.(*WhatIfRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_ListResourceTypes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListResourceTypesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).ListResourceTypes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/ListResourceTypes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).ListResourceTypes(ctx, req.(*ListResourceTypesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_PutResourceType_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResourceTypeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).PutResourceType(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/PutResourceType",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).PutResourceType(ctx, req.(*ResourceTypeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_DeleteResourceType_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteResourceTypeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).DeleteResourceType(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/DeleteResourceType",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).DeleteResourceType(ctx, req.(*DeleteResourceTypeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_AddPolicies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BulkPolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).AddPolicies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/AddPolicies",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).AddPolicies(ctx, req.(*BulkPolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_RemovePolicies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BulkPolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).RemovePolicies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/RemovePolicies",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).RemovePolicies(ctx, req.(*BulkPolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Access_ReplacePolicies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReplacePoliciesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessServer).ReplacePolicies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/access.Access/ReplacePolicies",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessServer).ReplacePolicies(ctx, req.(*ReplacePoliciesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Access_ServiceDesc is the grpc.ServiceDesc for Access service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -135,6 +807,90 @@ var Access_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Authorize",
 			Handler:    _Access_Authorize_Handler,
 		},
+		{
+			MethodName: "RequestAccess",
+			Handler:    _Access_RequestAccess_Handler,
+		},
+		{
+			MethodName: "ApproveAccess",
+			Handler:    _Access_ApproveAccess_Handler,
+		},
+		{
+			MethodName: "RejectAccess",
+			Handler:    _Access_RejectAccess_Handler,
+		},
+		{
+			MethodName: "ListAccessRequests",
+			Handler:    _Access_ListAccessRequests_Handler,
+		},
+		{
+			MethodName: "ListPermissions",
+			Handler:    _Access_ListPermissions_Handler,
+		},
+		{
+			MethodName: "ListSubjects",
+			Handler:    _Access_ListSubjects_Handler,
+		},
+		{
+			MethodName: "ListPolicies",
+			Handler:    _Access_ListPolicies_Handler,
+		},
+		{
+			MethodName: "AddPolicy",
+			Handler:    _Access_AddPolicy_Handler,
+		},
+		{
+			MethodName: "RemovePolicy",
+			Handler:    _Access_RemovePolicy_Handler,
+		},
+		{
+			MethodName: "ListRoles",
+			Handler:    _Access_ListRoles_Handler,
+		},
+		{
+			MethodName: "AssignRole",
+			Handler:    _Access_AssignRole_Handler,
+		},
+		{
+			MethodName: "UnassignRole",
+			Handler:    _Access_UnassignRole_Handler,
+		},
+		{
+			MethodName: "ExportPolicies",
+			Handler:    _Access_ExportPolicies_Handler,
+		},
+		{
+			MethodName: "ImportPolicies",
+			Handler:    _Access_ImportPolicies_Handler,
+		},
+		{
+			MethodName: "WhatIf",
+			Handler:    _Access_WhatIf_Handler,
+		},
+		{
+			MethodName: "ListResourceTypes",
+			Handler:    _Access_ListResourceTypes_Handler,
+		},
+		{
+			MethodName: "PutResourceType",
+			Handler:    _Access_PutResourceType_Handler,
+		},
+		{
+			MethodName: "DeleteResourceType",
+			Handler:    _Access_DeleteResourceType_Handler,
+		},
+		{
+			MethodName: "AddPolicies",
+			Handler:    _Access_AddPolicies_Handler,
+		},
+		{
+			MethodName: "RemovePolicies",
+			Handler:    _Access_RemovePolicies_Handler,
+		},
+		{
+			MethodName: "ReplacePolicies",
+			Handler:    _Access_ReplacePolicies_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "access.proto",
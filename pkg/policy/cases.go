@@ -0,0 +1,112 @@
+package policy
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/casbin/casbin/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// expectations of a Case
+const (
+	Allow = "allow"
+	Deny  = "deny"
+)
+
+// Case is an expected decision, in YAML e.g.
+// {subject: alice, resource: data, action: read, expect: allow}
+type Case struct {
+	Name     string `yaml:"name,omitempty"`
+	Subject  string `yaml:"subject"`
+	Resource string `yaml:"resource"`
+	Action   string `yaml:"action"`
+	Expect   string `yaml:"expect"`
+}
+
+func (c Case) String() string {
+	request := fmt.Sprintf("%s, %s, %s", c.Subject, c.Resource, c.Action)
+	if c.Name == "" {
+		return request
+	}
+	return c.Name + " (" + request + ")"
+}
+
+// ReadCases parses a YAML list of cases, under a cases key or at the top
+func ReadCases(r io.Reader) ([]Case, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Cases []Case `yaml:"cases"`
+	}
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		if err := yaml.UnmarshalStrict(data, &file.Cases); err != nil {
+			return nil, err
+		}
+	}
+	for i, c := range file.Cases {
+		switch {
+		case c.Subject == "" || c.Resource == "" || c.Action == "":
+			return nil, fmt.Errorf("case %d: subject, resource and action are required", i+1)
+		case c.Expect != Allow && c.Expect != Deny:
+			return nil, fmt.Errorf("case %d: expect must be %s or %s, not %q", i+1, Allow, Deny, c.Expect)
+		}
+	}
+	return file.Cases, nil
+}
+
+// Result is the decision on a Case, explained by the roles of its subject and
+// the rule that allowed it
+type Result struct {
+	Case
+	Allowed bool
+	Roles   []string
+	Matched []string
+	Err     error
+}
+
+// Passed reports whether the decision is the expected one
+func (r *Result) Passed() bool {
+	return r.Err == nil && r.Allowed == (r.Expect == Allow)
+}
+
+// String explains the result over a few lines
+func (r *Result) String() string {
+	var b strings.Builder
+	got := Deny
+	if r.Allowed {
+		got = Allow
+	}
+	if r.Err != nil {
+		got = "error: " + r.Err.Error()
+	}
+	fmt.Fprintf(&b, "%s: expected %s, got %s\n", r.Case, r.Expect, got)
+	roles := "none"
+	if len(r.Roles) > 0 {
+		roles = strings.Join(r.Roles, ", ")
+	}
+	fmt.Fprintf(&b, "    roles: %s\n", roles)
+	if len(r.Matched) > 0 {
+		fmt.Fprintf(&b, "    matched: %s\n", Rule{PType: "p", Values: r.Matched})
+	} else {
+		fmt.Fprintf(&b, "    matched: no rule\n")
+	}
+	return b.String()
+}
+
+// RunCases decides every case with e
+func RunCases(e *casbin.Enforcer, cases []Case) []*Result {
+	results := make([]*Result, 0, len(cases))
+	for _, c := range cases {
+		r := &Result{Case: c}
+		r.Allowed, r.Matched, r.Err = e.EnforceEx(c.Subject, c.Resource, c.Action)
+		if r.Err == nil {
+			r.Roles, r.Err = e.GetImplicitRolesForUser(c.Subject)
+		}
+		results = append(results, r)
+	}
+	return results
+}
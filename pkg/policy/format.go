@@ -0,0 +1,134 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// FormatCSV is the casbin policy file format, "p, alice, data, read"
+	FormatCSV = "csv"
+	// FormatJSON maps policy types to rules, {"p": [["alice", "data", "read"]]}
+	FormatJSON = "json"
+	// FormatYAML is FormatJSON in YAML
+	FormatYAML = "yaml"
+)
+
+// FormatFromPath picks the format of a file by extension
+func FormatFromPath(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".json":
+		return FormatJSON, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	}
+	return "", fmt.Errorf("%s: unknown policy format", path)
+}
+
+// Read parses a policy in format
+func Read(format string, r io.Reader) (Set, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	switch format {
+	case FormatCSV:
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.Comment = '#'
+		reader.TrimLeadingSpace = true
+		reader.FieldsPerRecord = -1
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			rule, err := parseRule(record[0], record[1:])
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, rule)
+		}
+		return NewSet(rules), nil
+	case FormatJSON, FormatYAML:
+		var types map[string][][]string
+		if format == FormatJSON {
+			err = json.Unmarshal(data, &types)
+		} else {
+			err = yaml.Unmarshal(data, &types)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for ptype, rows := range types {
+			for _, values := range rows {
+				rule, err := parseRule(ptype, values)
+				if err != nil {
+					return nil, err
+				}
+				rules = append(rules, rule)
+			}
+		}
+		return NewSet(rules), nil
+	}
+	return nil, fmt.Errorf("unknown policy format %q", format)
+}
+
+func parseRule(ptype string, values []string) (Rule, error) {
+	ptype = strings.TrimSpace(ptype)
+	if !strings.HasPrefix(ptype, "p") && !strings.HasPrefix(ptype, "g") {
+		return Rule{}, fmt.Errorf("unknown policy type %q", ptype)
+	}
+	if len(values) == 0 {
+		return Rule{}, fmt.Errorf("%s rule without values", ptype)
+	}
+	trimmed := make([]string, len(values))
+	for i, v := range values {
+		trimmed[i] = strings.TrimSpace(v)
+	}
+	return Rule{PType: ptype, Values: trimmed}, nil
+}
+
+// Write prints set in format, rules sorted so exports diff cleanly
+func Write(format string, w io.Writer, set Set) error {
+	rules := set.Rules()
+	switch format {
+	case FormatCSV:
+		for _, rule := range rules {
+			if _, err := fmt.Fprintln(w, rule.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sorted(rules))
+	case FormatYAML:
+		data, err := yaml.Marshal(sorted(rules))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	return fmt.Errorf("unknown policy format %q", format)
+}
+
+// sorted groups sorted rules by type, keeping their order
+func sorted(rules []Rule) map[string][][]string {
+	set := make(map[string][][]string)
+	for _, rule := range rules {
+		set[rule.PType] = append(set[rule.PType], rule.Values)
+	}
+	return set
+}
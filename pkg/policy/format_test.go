@@ -0,0 +1,112 @@
+package policy
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatFromPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "policy.csv", want: FormatCSV},
+		{path: "dir/policy.JSON", want: FormatJSON},
+		{path: "policy.yaml", want: FormatYAML},
+		{path: "policy.yml", want: FormatYAML},
+		{path: "policy.txt", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := FormatFromPath(tt.path)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("FormatFromPath(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	set := NewSet([]Rule{
+		rule("p", "alice", "data", "read"),
+		rule("p", "bob", "room/1", "join"),
+		rule("p", "carol", "a,b", `say "hi"`),
+		rule("g", "alice", "admin"),
+		rule("g2", "room/1", "rooms"),
+	})
+	for _, format := range []string{FormatCSV, FormatJSON, FormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(format, &buf, set); err != nil {
+				t.Fatal(err)
+			}
+			got, err := Read(format, &buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Rules(), set.Rules()) {
+				t.Errorf("read back %v, want %v", got.Rules(), set.Rules())
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		data    string
+		want    []Rule
+		wantErr bool
+	}{
+		{
+			name:   "csv",
+			format: FormatCSV,
+			data:   "# comment\np, alice, data, read\n\ng,  alice ,admin\np, alice, data, read\n",
+			want:   []Rule{rule("p", "alice", "data", "read"), rule("g", "alice", "admin")},
+		},
+		{
+			name:   "json",
+			format: FormatJSON,
+			data:   `{"p": [["alice", "data", "read"]], "g": [["alice", "admin"]]}`,
+			want:   []Rule{rule("p", "alice", "data", "read"), rule("g", "alice", "admin")},
+		},
+		{
+			name:   "yaml",
+			format: FormatYAML,
+			data:   "p:\n- [alice, data, read]\ng:\n- [alice, admin]\n",
+			want:   []Rule{rule("p", "alice", "data", "read"), rule("g", "alice", "admin")},
+		},
+		{name: "unknown type", format: FormatCSV, data: "x, alice, data\n", wantErr: true},
+		{name: "no values", format: FormatJSON, data: `{"p": [[]]}`, wantErr: true},
+		{name: "bad json", format: FormatJSON, data: `{"p": `, wantErr: true},
+		{name: "unknown format", format: "xml", data: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(tt.format, strings.NewReader(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Read() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if want := NewSet(tt.want).Rules(); !reflect.DeepEqual(got.Rules(), want) {
+				t.Errorf("Read() = %v, want %v", got.Rules(), want)
+			}
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	set := NewSet([]Rule{rule("g", "alice", "admin"), rule("p", "bob", "data", "read"), rule("p", "alice", "data", "read")})
+	var buf bytes.Buffer
+	if err := Write(FormatCSV, &buf, set); err != nil {
+		t.Fatal(err)
+	}
+	want := "p, alice, data, read\np, bob, data, read\ng, alice, admin\n"
+	if buf.String() != want {
+		t.Errorf("Write() = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,55 @@
+package policy
+
+import (
+	accesspb "github.com/piyush1104/access/pkg/internal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// grant is the part of a just-in-time access request KeepGrants reads. Granted
+// records whether the request added its rule, and so removes it on expiry.
+type grant struct {
+	ID       uint64
+	Subject  string
+	Resource string
+	Action   string
+	Status   accesspb.AccessRequestStatus
+	Granted  bool
+}
+
+func (grant) TableName() string {
+	return "access_requests"
+}
+
+// KeepGrants makes the p rules among rules permanent: approved requests
+// granting one of them no longer remove it when they expire. It locks the
+// requests until tx ends, and must be called before casbin_rule is written,
+// the order expiring grants locks them in.
+func KeepGrants(tx *gorm.DB, rules []Rule) error {
+	if len(rules) == 0 || !tx.Migrator().HasTable(&grant{}) {
+		return nil
+	}
+	var granted []grant
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("status = ? AND granted = ?", accesspb.AccessRequestStatus_ACCESS_REQUEST_STATUS_APPROVED, true).
+		Find(&granted).Error
+	if err != nil || len(granted) == 0 {
+		return err
+	}
+	kept := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if rule.PType == "p" {
+			kept[rule.key()] = true
+		}
+	}
+	var ids []uint64
+	for _, g := range granted {
+		if kept[Rule{PType: "p", Values: []string{g.Subject, g.Resource, g.Action}}.key()] {
+			ids = append(ids, g.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Model(&grant{}).Where("id IN ?", ids).Update("granted", false).Error
+}
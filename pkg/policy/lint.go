@@ -0,0 +1,344 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// actions the access server itself checks, in every vocabulary
+const (
+	// ApproveAction is needed on a resource to decide access requests for it
+	ApproveAction = "approve"
+	// AdminAction is needed on an admin object to call the RPC it guards
+	AdminAction = "call"
+)
+
+// LintConfig tunes the checks of Lint
+type LintConfig struct {
+	// Actions is the vocabulary p rules may use, any action when empty.
+	// ApproveAction and AdminAction are always in it.
+	Actions []string `mapstructure:"actions,omitempty"`
+	// RolePrefixes tell roles from users, e.g. "role:", so that roles
+	// nobody is assigned can be reported
+	RolePrefixes []string `mapstructure:"role_prefixes,omitempty"`
+}
+
+// severities of a Finding
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// checks reported in a Finding
+const (
+	CheckModelSyntax   = "model"
+	CheckPolicyType    = "policy_type"
+	CheckArity         = "arity"
+	CheckUnknownRole   = "unknown_role"
+	CheckRoleCycle     = "role_cycle"
+	CheckDuplicate     = "duplicate"
+	CheckShadowed      = "shadowed"
+	CheckUnusedRole    = "unused_role"
+	CheckUnknownAction = "unknown_action"
+)
+
+// Finding is a problem Lint found, in the rule it names when there is one
+type Finding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (f Finding) String() string {
+	if f.Rule == "" {
+		return fmt.Sprintf("%s %s: %s", f.Severity, f.Check, f.Message)
+	}
+	return fmt.Sprintf("%s %s: %s: %s", f.Severity, f.Check, f.Rule, f.Message)
+}
+
+// Lint checks a model and the rules of a policy, in store order with any
+// duplicates: the model must parse, rules must fit its definitions, roles
+// must grant something and not contain themselves, actions must be in the
+// vocabulary, and rules should be neither repeated nor granted again through
+// a role
+func Lint(text string, rules []Rule, config LintConfig) []Finding {
+	if err := CheckModel(text); err != nil {
+		return []Finding{{Check: CheckModelSyntax, Severity: SeverityError, Message: err.Error()}}
+	}
+	m, _ := model.NewModelFromString(text)
+	l := &linter{config: config}
+	l.definitions(m, rules)
+	l.duplicates(rules)
+
+	g := newRoleGraph(rules)
+	l.roles(g, rules)
+	l.cycles(g)
+	l.shadowed(g, rules)
+	l.actions(rules)
+	return l.findings
+}
+
+type linter struct {
+	config   LintConfig
+	findings []Finding
+}
+
+func (l *linter) add(check, severity string, rule *Rule, format string, args ...interface{}) {
+	f := Finding{Check: check, Severity: severity, Message: fmt.Sprintf(format, args...)}
+	if rule != nil {
+		f.Rule = rule.String()
+	}
+	l.findings = append(l.findings, f)
+}
+
+// definitions checks every rule has a policy type of the model and as many
+// values as its definition
+func (l *linter) definitions(m model.Model, rules []Rule) {
+	for i := range rules {
+		var err *RuleError
+		if errors.As(CheckRule(m, rules[i]), &err) {
+			l.add(err.check, SeverityError, &rules[i], "%s", err.message)
+		}
+	}
+}
+
+// RuleError is why a rule does not fit a model
+type RuleError struct {
+	check   string
+	message string
+}
+
+func (e *RuleError) Error() string {
+	return e.message
+}
+
+// CheckRule checks rule has a policy type of the model and as many values as
+// its definition
+func CheckRule(m model.Model, rule Rule) error {
+	var ast *model.Assertion
+	ok := rule.PType != ""
+	if ok {
+		ast, ok = m[rule.PType[:1]][rule.PType]
+	}
+	if !ok {
+		return &RuleError{CheckPolicyType, fmt.Sprintf("the model defines no policy type %q", rule.PType)}
+	}
+	want := len(ast.Tokens)
+	if rule.PType[0] == 'g' {
+		// role definitions are "_, _" with no tokens
+		want = strings.Count(ast.Value, "_")
+	}
+	if len(rule.Values) != want {
+		return &RuleError{CheckArity, fmt.Sprintf("%d values, the model defines %d", len(rule.Values), want)}
+	}
+	return nil
+}
+
+func (l *linter) duplicates(rules []Rule) {
+	seen := make(map[string]bool, len(rules))
+	for i := range rules {
+		key := rules[i].key()
+		if seen[key] {
+			l.add(CheckDuplicate, SeverityWarning, &rules[i], "repeats an earlier rule")
+		}
+		seen[key] = true
+	}
+}
+
+// roleGraph links the members of g links to their roles
+type roleGraph struct {
+	parents map[string][]string
+	members map[string]int
+	granted map[string]bool
+}
+
+func newRoleGraph(rules []Rule) *roleGraph {
+	g := &roleGraph{
+		parents: make(map[string][]string),
+		members: make(map[string]int),
+		granted: make(map[string]bool),
+	}
+	for _, rule := range rules {
+		switch {
+		case rule.PType == "g" && len(rule.Values) >= 2:
+			member, role := rule.Values[0], rule.Values[1]
+			g.parents[member] = append(g.parents[member], role)
+			g.members[role]++
+		case strings.HasPrefix(rule.PType, "p") && len(rule.Values) > 0:
+			g.granted[rule.Values[0]] = true
+		}
+	}
+	for member := range g.parents {
+		sort.Strings(g.parents[member])
+	}
+	return g
+}
+
+// ancestors lists every role subject inherits, directly or not
+func (g *roleGraph) ancestors(subject string) []string {
+	seen := map[string]bool{subject: true}
+	var out []string
+	queue := []string{subject}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, role := range g.parents[next] {
+			if !seen[role] {
+				seen[role] = true
+				out = append(out, role)
+				queue = append(queue, role)
+			}
+		}
+	}
+	return out
+}
+
+// roles reports g links to roles that grant nothing and, with role prefixes,
+// roles nobody is assigned
+func (l *linter) roles(g *roleGraph, rules []Rule) {
+	for i := range rules {
+		rule := &rules[i]
+		if rule.PType != "g" || len(rule.Values) < 2 {
+			continue
+		}
+		role := rule.Values[1]
+		if !g.granted[role] && len(g.parents[role]) == 0 {
+			l.add(CheckUnknownRole, SeverityError, rule, "role %s has no policy and inherits no role", role)
+		}
+	}
+	if len(l.config.RolePrefixes) == 0 {
+		return
+	}
+	subjects := make(map[string]bool)
+	for subject := range g.granted {
+		subjects[subject] = true
+	}
+	for member := range g.parents {
+		subjects[member] = true
+	}
+	for _, subject := range sortedKeys(subjects) {
+		if l.isRole(subject) && g.members[subject] == 0 {
+			l.add(CheckUnusedRole, SeverityWarning, nil, "role %s is assigned to nobody", subject)
+		}
+	}
+}
+
+func (l *linter) isRole(subject string) bool {
+	for _, prefix := range l.config.RolePrefixes {
+		if strings.HasPrefix(subject, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// cycles reports each loop of roles once, from its least role
+func (l *linter) cycles(g *roleGraph) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	reported := make(map[string]bool)
+	var path []string
+	var visit func(subject string)
+	visit = func(subject string) {
+		state[subject] = visiting
+		path = append(path, subject)
+		for _, role := range g.parents[subject] {
+			switch state[role] {
+			case unvisited:
+				visit(role)
+			case visiting:
+				var cycle []string
+				for i := len(path) - 1; i >= 0; i-- {
+					if path[i] == role {
+						cycle = append([]string(nil), path[i:]...)
+						break
+					}
+				}
+				cycle = rotateToLeast(cycle)
+				key := strings.Join(cycle, "\x00")
+				if !reported[key] {
+					reported[key] = true
+					l.add(CheckRoleCycle, SeverityError, nil, "roles inherit themselves: %s -> %s", strings.Join(cycle, " -> "), cycle[0])
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[subject] = done
+	}
+	members := make(map[string]bool, len(g.parents))
+	for member := range g.parents {
+		members[member] = true
+	}
+	for _, member := range sortedKeys(members) {
+		if state[member] == unvisited {
+			visit(member)
+		}
+	}
+}
+
+func rotateToLeast(cycle []string) []string {
+	least := 0
+	for i, role := range cycle {
+		if role < cycle[least] {
+			least = i
+		}
+	}
+	return append(cycle[least:], cycle[:least]...)
+}
+
+// shadowed reports p rules a subject also holds through one of its roles
+func (l *linter) shadowed(g *roleGraph, rules []Rule) {
+	grants := make(map[string]bool)
+	for _, rule := range rules {
+		if rule.PType == "p" {
+			grants[rule.key()] = true
+		}
+	}
+	for i := range rules {
+		rule := &rules[i]
+		if rule.PType != "p" || len(rule.Values) < 2 {
+			continue
+		}
+		for _, role := range g.ancestors(rule.Values[0]) {
+			values := append([]string{role}, rule.Values[1:]...)
+			if grants[Rule{PType: "p", Values: values}.key()] {
+				l.add(CheckShadowed, SeverityWarning, rule, "also granted through role %s", role)
+				break
+			}
+		}
+	}
+}
+
+func (l *linter) actions(rules []Rule) {
+	if len(l.config.Actions) == 0 {
+		return
+	}
+	vocabulary := map[string]bool{ApproveAction: true, AdminAction: true}
+	for _, action := range l.config.Actions {
+		vocabulary[action] = true
+	}
+	for i := range rules {
+		rule := &rules[i]
+		if rule.PType == "p" && len(rule.Values) >= 3 && !vocabulary[rule.Values[2]] {
+			l.add(CheckUnknownAction, SeverityError, rule, "action %s is not in the vocabulary", rule.Values[2])
+		}
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
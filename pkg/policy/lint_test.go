@@ -0,0 +1,168 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+`
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name   string
+		model  string
+		rules  []Rule
+		config LintConfig
+		want   []Finding
+	}{
+		{
+			name: "clean",
+			rules: []Rule{
+				rule("p", "role:reader", "data", "read"),
+				rule("g", "alice", "role:reader"),
+			},
+			config: LintConfig{Actions: []string{"read"}, RolePrefixes: []string{"role:"}},
+		},
+		{
+			name:  "model syntax",
+			model: "[request_definition]\nr = sub, obj, act\n",
+			rules: []Rule{rule("p", "alice", "data", "read")},
+			want:  []Finding{{Check: CheckModelSyntax, Severity: SeverityError}},
+		},
+		{
+			name:  "policy type",
+			rules: []Rule{rule("p2", "alice", "data", "read")},
+			want:  []Finding{{Check: CheckPolicyType, Severity: SeverityError, Rule: "p2, alice, data, read"}},
+		},
+		{
+			name:  "arity",
+			rules: []Rule{rule("p", "alice", "data"), rule("g", "alice", "bob", "domain")},
+			want: []Finding{
+				{Check: CheckArity, Severity: SeverityError, Rule: "p, alice, data"},
+				{Check: CheckArity, Severity: SeverityError, Rule: "g, alice, bob, domain"},
+				{Check: CheckUnknownRole, Severity: SeverityError, Rule: "g, alice, bob, domain"},
+			},
+		},
+		{
+			name:  "duplicate",
+			rules: []Rule{rule("p", "alice", "data", "read"), rule("p", "alice", "data", "read")},
+			want:  []Finding{{Check: CheckDuplicate, Severity: SeverityWarning, Rule: "p, alice, data, read"}},
+		},
+		{
+			name:  "unknown role",
+			rules: []Rule{rule("g", "alice", "role:ghost")},
+			want:  []Finding{{Check: CheckUnknownRole, Severity: SeverityError, Rule: "g, alice, role:ghost"}},
+		},
+		{
+			name: "role inheriting a role",
+			rules: []Rule{
+				rule("p", "role:reader", "data", "read"),
+				rule("g", "role:writer", "role:reader"),
+				rule("g", "alice", "role:writer"),
+			},
+		},
+		{
+			name: "cycle",
+			rules: []Rule{
+				rule("g", "role:b", "role:c"),
+				rule("g", "role:c", "role:a"),
+				rule("g", "role:a", "role:b"),
+				rule("g", "alice", "role:a"),
+			},
+			want: []Finding{{Check: CheckRoleCycle, Severity: SeverityError}},
+		},
+		{
+			name: "shadowed",
+			rules: []Rule{
+				rule("p", "role:reader", "data", "read"),
+				rule("g", "alice", "role:reader"),
+				rule("p", "alice", "data", "read"),
+				rule("p", "alice", "data", "write"),
+			},
+			want: []Finding{{Check: CheckShadowed, Severity: SeverityWarning, Rule: "p, alice, data, read"}},
+		},
+		{
+			name: "shadowed through an inherited role",
+			rules: []Rule{
+				rule("p", "role:reader", "data", "read"),
+				rule("g", "role:writer", "role:reader"),
+				rule("g", "alice", "role:writer"),
+				rule("p", "alice", "data", "read"),
+			},
+			want: []Finding{{Check: CheckShadowed, Severity: SeverityWarning, Rule: "p, alice, data, read"}},
+		},
+		{
+			name: "unused role",
+			rules: []Rule{
+				rule("p", "role:reader", "data", "read"),
+				rule("p", "alice", "data", "read"),
+			},
+			config: LintConfig{RolePrefixes: []string{"role:"}},
+			want:   []Finding{{Check: CheckUnusedRole, Severity: SeverityWarning}},
+		},
+		{
+			name: "unknown action",
+			rules: []Rule{
+				rule("p", "alice", "data", "read"),
+				rule("p", "alice", "data", "erase"),
+				rule("p", "alice", "data", "approve"),
+				rule("p", "alice", "access:rpc/ApproveAccess", "call"),
+			},
+			config: LintConfig{Actions: []string{"read", "write"}},
+			want:   []Finding{{Check: CheckUnknownAction, Severity: SeverityError, Rule: "p, alice, data, erase"}},
+		},
+		{
+			name:  "any action without a vocabulary",
+			rules: []Rule{rule("p", "alice", "data", "erase")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := tt.model
+			if model == "" {
+				model = testModel
+			}
+			got := Lint(model, tt.rules, tt.config)
+			// messages are for people, the check, severity and rule are
+			// what is asserted
+			for i := range got {
+				got[i].Message = ""
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintCycleMessage(t *testing.T) {
+	rules := []Rule{
+		rule("g", "role:b", "role:c"),
+		rule("g", "role:c", "role:a"),
+		rule("g", "role:a", "role:b"),
+	}
+	got := Lint(testModel, rules, LintConfig{})
+	if len(got) != 1 {
+		t.Fatalf("Lint() = %v, want one cycle", got)
+	}
+	// the cycle is reported once, from its least role
+	want := "roles inherit themselves: role:a -> role:b -> role:c -> role:a"
+	if got[0].Message != want {
+		t.Errorf("message = %q, want %q", got[0].Message, want)
+	}
+}
@@ -0,0 +1,96 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// LoadModel reads the model at path, checking that its sections parse and its
+// matcher evaluates
+func LoadModel(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	text := string(data)
+	if err := CheckModel(text); err != nil {
+		return "", fmt.Errorf("%s: %v", path, err)
+	}
+	return text, nil
+}
+
+// CheckModel checks that the sections of a model parse and its matcher
+// evaluates
+func CheckModel(text string) error {
+	m, err := model.NewModelFromString(text)
+	if err != nil {
+		return err
+	}
+	e, err := casbin.NewEnforcer(m)
+	if err != nil {
+		return err
+	}
+	_, err = e.Enforce("", "", "")
+	return err
+}
+
+// NewEnforcer builds an enforcer of the model text over the rules of set, no
+// database needed
+func NewEnforcer(text string, set Set) (*casbin.Enforcer, error) {
+	m, err := model.NewModelFromString(text)
+	if err != nil {
+		return nil, err
+	}
+	return casbin.NewEnforcer(m, &setAdapter{set: set})
+}
+
+var errReadOnly = errors.New("policy set is read only")
+
+// setAdapter loads the policy from a Set, refusing changes
+type setAdapter struct {
+	set Set
+}
+
+var _ persist.Adapter = (*setAdapter)(nil)
+
+func (a *setAdapter) LoadPolicy(m model.Model) error {
+	for _, rule := range a.set.Rules() {
+		persist.LoadPolicyArray(append([]string{rule.PType}, rule.Values...), m)
+	}
+	return nil
+}
+
+func (a *setAdapter) SavePolicy(model.Model) error {
+	return errReadOnly
+}
+
+func (a *setAdapter) AddPolicy(string, string, []string) error {
+	return errReadOnly
+}
+
+func (a *setAdapter) RemovePolicy(string, string, []string) error {
+	return errReadOnly
+}
+
+func (a *setAdapter) RemoveFilteredPolicy(string, string, int, ...string) error {
+	return errReadOnly
+}
+
+// FromEnforcer copies the policy e holds, p rules and g links alike
+func FromEnforcer(e *casbin.Enforcer) Set {
+	set := Set{}
+	m := e.GetModel()
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range m[sec] {
+			for _, values := range ast.Policy {
+				set[ptype] = append(set[ptype], append([]string(nil), values...))
+			}
+		}
+	}
+	return set
+}
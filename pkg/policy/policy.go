@@ -0,0 +1,205 @@
+package policy
+
+import (
+	"sort"
+	"strings"
+)
+
+// Rule is one policy rule, p rules and g role links alike, as in a casbin
+// CSV line
+type Rule struct {
+	PType  string
+	Values []string
+}
+
+// String is the rule as a casbin CSV line, e.g. "p, alice, data, read"
+func (r Rule) String() string {
+	fields := make([]string, 0, len(r.Values)+1)
+	fields = append(fields, r.PType)
+	for _, v := range r.Values {
+		if strings.ContainsAny(v, ",\"\n") {
+			v = `"` + strings.ReplaceAll(v, `"`, `""`) + `"`
+		}
+		fields = append(fields, v)
+	}
+	return strings.Join(fields, ", ")
+}
+
+func (r Rule) key() string {
+	return r.PType + "\x00" + strings.Join(r.Values, "\x00")
+}
+
+// Set is a complete policy, the rules of each policy type, e.g.
+// {"p": [["alice", "data", "read"]], "g": [["alice", "admin"]]}
+type Set map[string][][]string
+
+// NewSet makes a set of rules, dropping repeated ones
+func NewSet(rules []Rule) Set {
+	set := Set{}
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if key := rule.key(); !seen[key] {
+			seen[key] = true
+			set[rule.PType] = append(set[rule.PType], rule.Values)
+		}
+	}
+	return set
+}
+
+// Add adds a rule unless the set has it already. It scans the rules of the
+// type, so sets of many rules are built with NewSet instead.
+func (s Set) Add(rule Rule) bool {
+	if s.Has(rule) {
+		return false
+	}
+	s[rule.PType] = append(s[rule.PType], rule.Values)
+	return true
+}
+
+// Has tells whether the set holds rule
+func (s Set) Has(rule Rule) bool {
+	key := rule.key()
+	for _, values := range s[rule.PType] {
+		if (Rule{PType: rule.PType, Values: values}).key() == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Rules lists the rules of the set, p rules first then by type and values
+func (s Set) Rules() []Rule {
+	var rules []Rule
+	for ptype, rows := range s {
+		for _, values := range rows {
+			rules = append(rules, Rule{PType: ptype, Values: values})
+		}
+	}
+	sortRules(rules)
+	return rules
+}
+
+// Len is the number of rules in the set
+func (s Set) Len() int {
+	n := 0
+	for _, rows := range s {
+		n += len(rows)
+	}
+	return n
+}
+
+func sortRules(rules []Rule) {
+	sort.Slice(rules, func(i, j int) bool {
+		a, b := rules[i], rules[j]
+		if a.PType != b.PType {
+			// policies before role links
+			if pa, pb := strings.HasPrefix(a.PType, "p"), strings.HasPrefix(b.PType, "p"); pa != pb {
+				return pa
+			}
+			return a.PType < b.PType
+		}
+		for k := 0; k < len(a.Values) && k < len(b.Values); k++ {
+			if a.Values[k] != b.Values[k] {
+				return a.Values[k] < b.Values[k]
+			}
+		}
+		return len(a.Values) < len(b.Values)
+	})
+}
+
+// Diff is what changes a store into a desired set
+type Diff struct {
+	Add    []Rule
+	Remove []Rule
+}
+
+// Empty tells whether the diff changes nothing
+func (d *Diff) Empty() bool {
+	return len(d.Add) == 0 && len(d.Remove) == 0
+}
+
+// String prints the diff one rule per line, "+ " for added rules and "- " for
+// removed ones
+func (d *Diff) String() string {
+	var b strings.Builder
+	for _, rule := range d.Remove {
+		b.WriteString("- " + rule.String() + "\n")
+	}
+	for _, rule := range d.Add {
+		b.WriteString("+ " + rule.String() + "\n")
+	}
+	return b.String()
+}
+
+func (s Set) keys() map[string]bool {
+	keys := make(map[string]bool, s.Len())
+	for ptype, rows := range s {
+		for _, values := range rows {
+			keys[Rule{PType: ptype, Values: values}.key()] = true
+		}
+	}
+	return keys
+}
+
+// Merge diffs current against desired adding the missing rules only
+func Merge(current, desired Set) *Diff {
+	d := &Diff{}
+	have := current.keys()
+	for _, rule := range desired.Rules() {
+		if !have[rule.key()] {
+			d.Add = append(d.Add, rule)
+		}
+	}
+	return d
+}
+
+// Subtract diffs current against removing the rules of unwanted it has
+func Subtract(current, unwanted Set) *Diff {
+	d := &Diff{}
+	have := current.keys()
+	for _, rule := range unwanted.Rules() {
+		if have[rule.key()] {
+			d.Remove = append(d.Remove, rule)
+		}
+	}
+	return d
+}
+
+// Replace diffs current against desired adding the missing rules and removing
+// the rules desired does not have
+func Replace(current, desired Set) *Diff {
+	d := Merge(current, desired)
+	want := desired.keys()
+	for _, rule := range current.Rules() {
+		if !want[rule.key()] {
+			d.Remove = append(d.Remove, rule)
+		}
+	}
+	return d
+}
+
+// Patch returns a copy of s changed by the diff, s itself unchanged
+func (d *Diff) Patch(s Set) Set {
+	remove := make(map[string]bool, len(d.Remove))
+	for _, rule := range d.Remove {
+		remove[rule.key()] = true
+	}
+	out := Set{}
+	have := make(map[string]bool, s.Len())
+	for ptype, rows := range s {
+		for _, values := range rows {
+			key := Rule{PType: ptype, Values: values}.key()
+			if !remove[key] {
+				out[ptype] = append(out[ptype], values)
+				have[key] = true
+			}
+		}
+	}
+	for _, rule := range d.Add {
+		if !have[rule.key()] {
+			out[rule.PType] = append(out[rule.PType], rule.Values)
+			have[rule.key()] = true
+		}
+	}
+	return out
+}
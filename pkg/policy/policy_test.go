@@ -0,0 +1,183 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func rule(ptype string, values ...string) Rule {
+	return Rule{PType: ptype, Values: values}
+}
+
+func TestRuleString(t *testing.T) {
+	tests := []struct {
+		rule Rule
+		want string
+	}{
+		{rule("p", "alice", "data", "read"), "p, alice, data, read"},
+		{rule("g", "alice", "admin"), "g, alice, admin"},
+		{rule("p", "alice", "a,b", "read"), `p, alice, "a,b", read`},
+		{rule("p", "alice", `say "hi"`, "read"), `p, alice, "say ""hi""", read`},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestNewSet(t *testing.T) {
+	set := NewSet([]Rule{
+		rule("p", "alice", "data", "read"),
+		rule("g", "alice", "admin"),
+		rule("p", "alice", "data", "read"),
+	})
+	if set.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", set.Len())
+	}
+	if !set.Has(rule("p", "alice", "data", "read")) || !set.Has(rule("g", "alice", "admin")) {
+		t.Errorf("set %v misses a rule", set)
+	}
+	if set.Has(rule("p", "alice", "data", "write")) {
+		t.Errorf("set %v has a rule it was not given", set)
+	}
+	if set.Add(rule("g", "alice", "admin")) {
+		t.Error("Add of a held rule reported a change")
+	}
+	if !set.Add(rule("g", "bob", "admin")) || set.Len() != 3 {
+		t.Error("Add of a new rule did not add it")
+	}
+}
+
+func TestSetRulesOrder(t *testing.T) {
+	set := NewSet([]Rule{
+		rule("g", "bob", "admin"),
+		rule("p", "bob", "data", "read"),
+		rule("g2", "data", "group"),
+		rule("p", "alice", "data", "write"),
+		rule("p", "alice", "data"),
+	})
+	want := []Rule{
+		rule("p", "alice", "data"),
+		rule("p", "alice", "data", "write"),
+		rule("p", "bob", "data", "read"),
+		rule("g", "bob", "admin"),
+		rule("g2", "data", "group"),
+	}
+	if got := set.Rules(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Rules() = %v, want %v", got, want)
+	}
+}
+
+func TestDiffs(t *testing.T) {
+	current := NewSet([]Rule{
+		rule("p", "alice", "data", "read"),
+		rule("p", "bob", "data", "read"),
+		rule("g", "alice", "admin"),
+	})
+	other := NewSet([]Rule{
+		rule("p", "alice", "data", "read"),
+		rule("p", "carol", "data", "write"),
+		rule("g", "alice", "admin"),
+	})
+
+	tests := []struct {
+		name string
+		diff *Diff
+		want *Diff
+	}{
+		{
+			name: "merge",
+			diff: Merge(current, other),
+			want: &Diff{Add: []Rule{rule("p", "carol", "data", "write")}},
+		},
+		{
+			name: "subtract",
+			diff: Subtract(current, other),
+			want: &Diff{Remove: []Rule{rule("p", "alice", "data", "read"), rule("g", "alice", "admin")}},
+		},
+		{
+			name: "replace",
+			diff: Replace(current, other),
+			want: &Diff{
+				Add:    []Rule{rule("p", "carol", "data", "write")},
+				Remove: []Rule{rule("p", "bob", "data", "read")},
+			},
+		},
+		{
+			name: "replace with itself",
+			diff: Replace(current, current),
+			want: &Diff{},
+		},
+		{
+			name: "merge into empty",
+			diff: Merge(Set{}, NewSet([]Rule{rule("g", "alice", "admin")})),
+			want: &Diff{Add: []Rule{rule("g", "alice", "admin")}},
+		},
+		{
+			name: "subtract missing",
+			diff: Subtract(current, NewSet([]Rule{rule("p", "dave", "data", "read")})),
+			want: &Diff{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.diff, tt.want) {
+				t.Errorf("diff = %+v, want %+v", tt.diff, tt.want)
+			}
+			if tt.diff.Empty() != (len(tt.want.Add) == 0 && len(tt.want.Remove) == 0) {
+				t.Errorf("Empty() = %v", tt.diff.Empty())
+			}
+		})
+	}
+}
+
+func TestPatch(t *testing.T) {
+	current := NewSet([]Rule{
+		rule("p", "alice", "data", "read"),
+		rule("p", "bob", "data", "read"),
+		rule("g", "alice", "admin"),
+	})
+	desired := NewSet([]Rule{
+		rule("p", "alice", "data", "read"),
+		rule("p", "carol", "data", "write"),
+	})
+
+	tests := []struct {
+		name string
+		diff *Diff
+		want Set
+	}{
+		{name: "replace", diff: Replace(current, desired), want: desired},
+		{name: "merge", diff: Merge(current, desired), want: NewSet(append(current.Rules(), rule("p", "carol", "data", "write")))},
+		{name: "empty", diff: &Diff{}, want: current},
+		{
+			name: "add held rule",
+			diff: &Diff{Add: []Rule{rule("g", "alice", "admin")}},
+			want: current,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := current.Len()
+			got := tt.diff.Patch(current)
+			if !reflect.DeepEqual(got.Rules(), tt.want.Rules()) {
+				t.Errorf("Patch() = %v, want %v", got.Rules(), tt.want.Rules())
+			}
+			if current.Len() != before {
+				t.Error("Patch changed the set it was given")
+			}
+		})
+	}
+}
+
+func TestDiffString(t *testing.T) {
+	d := &Diff{
+		Add:    []Rule{rule("p", "carol", "data", "write")},
+		Remove: []Rule{rule("g", "bob", "admin")},
+	}
+	want := "- g, bob, admin\n+ p, carol, data, write\n"
+	if got := d.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
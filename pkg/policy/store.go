@@ -0,0 +1,122 @@
+package policy
+
+import (
+	"fmt"
+
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxValues is the number of value columns of casbin_rule
+const maxValues = 8
+
+// Load reads the complete policy from the casbin_rule table
+func Load(db *gorm.DB) (Set, error) {
+	rules, err := LoadRules(db)
+	if err != nil {
+		return nil, err
+	}
+	return NewSet(rules), nil
+}
+
+// LoadRules reads every row of the casbin_rule table in order, duplicates
+// included
+func LoadRules(db *gorm.DB) ([]Rule, error) {
+	var lines []gormadapter.CasbinRule
+	if err := db.Order("id").Find(&lines).Error; err != nil {
+		return nil, err
+	}
+	rules := make([]Rule, 0, len(lines))
+	for _, line := range lines {
+		values := []string{line.V0, line.V1, line.V2, line.V3, line.V4, line.V5, line.V6, line.V7}
+		// trailing empty columns are not part of the rule
+		n := len(values)
+		for n > 0 && values[n-1] == "" {
+			n--
+		}
+		rules = append(rules, Rule{PType: line.Ptype, Values: values[:n]})
+	}
+	return rules, nil
+}
+
+func row(rule Rule) (gormadapter.CasbinRule, error) {
+	if len(rule.Values) > maxValues {
+		return gormadapter.CasbinRule{}, fmt.Errorf("%s: more than %d values", rule, maxValues)
+	}
+	values := make([]string, maxValues)
+	copy(values, rule.Values)
+	return gormadapter.CasbinRule{
+		Ptype: rule.PType,
+		V0:    values[0],
+		V1:    values[1],
+		V2:    values[2],
+		V3:    values[3],
+		V4:    values[4],
+		V5:    values[5],
+		V6:    values[6],
+		V7:    values[7],
+	}, nil
+}
+
+// Import diffs the casbin_rule table against desired, merging or replacing,
+// and applies the diff unless dryRun, in one transaction holding the rows
+// read so concurrent imports do not interleave. Imported rules stay after
+// just-in-time grants of them expire.
+func Import(db *gorm.DB, desired Set, replace, dryRun bool) (*Diff, error) {
+	var diff *Diff
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if !dryRun {
+			if err := KeepGrants(tx, desired.Rules()); err != nil {
+				return err
+			}
+		}
+		current, err := Load(tx.Clauses(clause.Locking{Strength: "UPDATE"}))
+		if err != nil {
+			return err
+		}
+		if replace {
+			diff = Replace(current, desired)
+		} else {
+			diff = Merge(current, desired)
+		}
+		if dryRun || diff.Empty() {
+			return nil
+		}
+		return Apply(tx, diff)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// Apply changes the casbin_rule table by diff in one transaction, so either
+// every rule changes or none does
+func Apply(db *gorm.DB, diff *Diff) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, rule := range diff.Remove {
+			r, err := row(rule)
+			if err != nil {
+				return err
+			}
+			err = tx.Where("ptype = ? AND v0 = ? AND v1 = ? AND v2 = ? AND v3 = ? AND v4 = ? AND v5 = ? AND v6 = ? AND v7 = ?",
+				r.Ptype, r.V0, r.V1, r.V2, r.V3, r.V4, r.V5, r.V6, r.V7).Delete(&gormadapter.CasbinRule{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(diff.Add) == 0 {
+			return nil
+		}
+		rows := make([]gormadapter.CasbinRule, 0, len(diff.Add))
+		for _, rule := range diff.Add {
+			r, err := row(rule)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, r)
+		}
+		return tx.CreateInBatches(rows, 1000).Error
+	})
+}
@@ -0,0 +1,122 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// testDB opens an in-memory store holding rules
+func testDB(t *testing.T, rules ...Rule) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection would open its own in-memory database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&gormadapter.CasbinRule{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Apply(db, &Diff{Add: rules}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestLoadRules(t *testing.T) {
+	db := testDB(t, rule("p", "alice", "data", "read"), rule("g", "alice", "admin"))
+	// a duplicate row, as the adapter may leave
+	if err := db.Create(&gormadapter.CasbinRule{Ptype: "g", V0: "alice", V1: "admin"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	rules, err := LoadRules(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Rule{rule("p", "alice", "data", "read"), rule("g", "alice", "admin"), rule("g", "alice", "admin")}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("LoadRules() = %v, want %v", rules, want)
+	}
+	set, err := Load(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Len() != 2 {
+		t.Errorf("Load() = %v, want duplicates dropped", set.Rules())
+	}
+}
+
+func TestImport(t *testing.T) {
+	current := []Rule{rule("p", "alice", "data", "read"), rule("g", "alice", "admin")}
+	desired := NewSet([]Rule{rule("p", "alice", "data", "read"), rule("p", "bob", "data", "write")})
+
+	tests := []struct {
+		name    string
+		replace bool
+		dryRun  bool
+		diff    *Diff
+		after   []Rule
+	}{
+		{
+			name:  "merge",
+			diff:  &Diff{Add: []Rule{rule("p", "bob", "data", "write")}},
+			after: []Rule{rule("p", "alice", "data", "read"), rule("p", "bob", "data", "write"), rule("g", "alice", "admin")},
+		},
+		{
+			name:    "replace",
+			replace: true,
+			diff:    &Diff{Add: []Rule{rule("p", "bob", "data", "write")}, Remove: []Rule{rule("g", "alice", "admin")}},
+			after:   []Rule{rule("p", "alice", "data", "read"), rule("p", "bob", "data", "write")},
+		},
+		{
+			name:    "dry run",
+			replace: true,
+			dryRun:  true,
+			diff:    &Diff{Add: []Rule{rule("p", "bob", "data", "write")}, Remove: []Rule{rule("g", "alice", "admin")}},
+			after:   current,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t, current...)
+			diff, err := Import(db, desired, tt.replace, tt.dryRun)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(diff, tt.diff) {
+				t.Errorf("Import() = %+v, want %+v", diff, tt.diff)
+			}
+			set, err := Load(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := NewSet(tt.after).Rules(); !reflect.DeepEqual(set.Rules(), want) {
+				t.Errorf("store holds %v, want %v", set.Rules(), want)
+			}
+		})
+	}
+}
+
+func TestApplyTooManyValues(t *testing.T) {
+	db := testDB(t, rule("p", "alice", "data", "read"))
+	long := rule("p", "a", "b", "c", "d", "e", "f", "g", "h", "i")
+	if err := Apply(db, &Diff{Add: []Rule{rule("p", "bob", "data", "read"), long}}); err == nil {
+		t.Fatal("Apply() of a rule with too many values succeeded")
+	}
+	set, err := Load(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Len() != 1 {
+		t.Errorf("failed Apply() left %v", set.Rules())
+	}
+}
@@ -0,0 +1,160 @@
+package policy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// managedRule marks a rule of casbin_rule as owned by the synced set, so a
+// later sync may remove it. Rules added any other way, e.g. by just-in-time
+// grants or the admin RPCs, are never removed without force.
+type managedRule struct {
+	Key  string `gorm:"primaryKey;size:64"`
+	Rule string `gorm:"size:1024"`
+}
+
+func (managedRule) TableName() string {
+	return "managed_rules"
+}
+
+func (r Rule) hash() string {
+	sum := sha256.Sum256([]byte(r.key()))
+	return hex.EncodeToString(sum[:])
+}
+
+// ReadDir reads every csv, json and yaml policy file under dir into one set
+func ReadDir(dir string) (Set, error) {
+	var all []Rule
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		format, err := FormatFromPath(path)
+		if err != nil {
+			// not a policy file
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		rules, err := Read(format, f)
+		if err != nil {
+			return &fs.PathError{Op: "read", Path: path, Err: err}
+		}
+		all = append(all, rules.Rules()...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewSet(all), nil
+}
+
+// Plan is what a sync changes: the diff applied, and the rules missing from
+// the synced set that are kept because the set does not own them
+type Plan struct {
+	Diff
+	Unmanaged []Rule
+}
+
+// String prints the plan as a diff, unmanaged rules kept marked with "! "
+func (p *Plan) String() string {
+	var b strings.Builder
+	b.WriteString(p.Diff.String())
+	for _, rule := range p.Unmanaged {
+		b.WriteString("! " + rule.String() + " (unmanaged, kept)\n")
+	}
+	return b.String()
+}
+
+// Sync makes casbin_rule hold desired, in one transaction: missing rules are
+// added and rules no longer desired are removed when a previous sync added
+// them, or with force, whatever added them. Every desired rule is owned by the
+// synced set afterwards, and stays after just-in-time grants of it expire. A
+// dry run only plans.
+func Sync(db *gorm.DB, desired Set, force, dryRun bool) (*Plan, error) {
+	if !dryRun {
+		if err := db.AutoMigrate(&managedRule{}); err != nil {
+			return nil, err
+		}
+	}
+
+	plan := &Plan{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if !dryRun {
+			if err := KeepGrants(tx, desired.Rules()); err != nil {
+				return err
+			}
+		}
+		current, err := Load(tx.Clauses(clause.Locking{Strength: "UPDATE"}))
+		if err != nil {
+			return err
+		}
+		managed := make(map[string]bool)
+		if tx.Migrator().HasTable(&managedRule{}) {
+			var rows []managedRule
+			if err := tx.Find(&rows).Error; err != nil {
+				return err
+			}
+			for _, row := range rows {
+				managed[row.Key] = true
+			}
+		}
+
+		diff := Replace(current, desired)
+		plan.Add = diff.Add
+		for _, rule := range diff.Remove {
+			if force || managed[rule.hash()] {
+				plan.Remove = append(plan.Remove, rule)
+			} else {
+				plan.Unmanaged = append(plan.Unmanaged, rule)
+			}
+		}
+		if dryRun {
+			return nil
+		}
+		if err := Apply(tx, &plan.Diff); err != nil {
+			return err
+		}
+
+		// the synced set owns exactly the desired rules
+		want := make(map[string]Rule, desired.Len())
+		for _, rule := range desired.Rules() {
+			want[rule.hash()] = rule
+		}
+		var stale []string
+		for key := range managed {
+			if _, ok := want[key]; !ok {
+				stale = append(stale, key)
+			}
+		}
+		if len(stale) > 0 {
+			if err := tx.Where(map[string]interface{}{"key": stale}).Delete(&managedRule{}).Error; err != nil {
+				return err
+			}
+		}
+		var owned []managedRule
+		for key, rule := range want {
+			if !managed[key] {
+				owned = append(owned, managedRule{Key: key, Rule: rule.String()})
+			}
+		}
+		if len(owned) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(owned, 1000).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
@@ -0,0 +1,140 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSync(t *testing.T) {
+	// alice read was synced before, carol read added through the admin RPCs
+	synced := []Rule{rule("p", "alice", "data", "read"), rule("g", "alice", "admin")}
+	unmanaged := rule("p", "carol", "data", "read")
+	desired := NewSet([]Rule{rule("g", "alice", "admin"), rule("p", "bob", "data", "write")})
+
+	tests := []struct {
+		name   string
+		force  bool
+		dryRun bool
+		want   *Plan
+		after  []Rule
+	}{
+		{
+			name: "managed removed, unmanaged kept",
+			want: &Plan{
+				Diff:      Diff{Add: []Rule{rule("p", "bob", "data", "write")}, Remove: []Rule{rule("p", "alice", "data", "read")}},
+				Unmanaged: []Rule{unmanaged},
+			},
+			after: []Rule{rule("g", "alice", "admin"), rule("p", "bob", "data", "write"), unmanaged},
+		},
+		{
+			name:  "force",
+			force: true,
+			want: &Plan{
+				Diff: Diff{
+					Add:    []Rule{rule("p", "bob", "data", "write")},
+					Remove: []Rule{rule("p", "alice", "data", "read"), unmanaged},
+				},
+			},
+			after: []Rule{rule("g", "alice", "admin"), rule("p", "bob", "data", "write")},
+		},
+		{
+			name:   "dry run",
+			dryRun: true,
+			want: &Plan{
+				Diff:      Diff{Add: []Rule{rule("p", "bob", "data", "write")}, Remove: []Rule{rule("p", "alice", "data", "read")}},
+				Unmanaged: []Rule{unmanaged},
+			},
+			after: append(append([]Rule(nil), synced...), unmanaged),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t)
+			if _, err := Sync(db, NewSet(synced), false, false); err != nil {
+				t.Fatal(err)
+			}
+			if err := Apply(db, &Diff{Add: []Rule{unmanaged}}); err != nil {
+				t.Fatal(err)
+			}
+
+			plan, err := Sync(db, desired, tt.force, tt.dryRun)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(plan, tt.want) {
+				t.Errorf("Sync() = %+v, want %+v", plan, tt.want)
+			}
+			set, err := Load(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := NewSet(tt.after).Rules(); !reflect.DeepEqual(set.Rules(), want) {
+				t.Errorf("store holds %v, want %v", set.Rules(), want)
+			}
+		})
+	}
+}
+
+func TestSyncOwnsDesiredRules(t *testing.T) {
+	db := testDB(t, rule("p", "alice", "data", "read"))
+	// a rule the store had before is owned once the synced set has it
+	if _, err := Sync(db, NewSet([]Rule{rule("p", "alice", "data", "read")}), false, false); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := Sync(db, Set{}, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Rule{rule("p", "alice", "data", "read")}; !reflect.DeepEqual(plan.Remove, want) || len(plan.Unmanaged) != 0 {
+		t.Errorf("Sync() = %+v, want %v removed", plan, want)
+	}
+	// and no longer owned once removed, a rule added again later being
+	// unmanaged
+	if err := Apply(db, &Diff{Add: []Rule{rule("p", "alice", "data", "read")}}); err != nil {
+		t.Fatal(err)
+	}
+	plan, err = Sync(db, Set{}, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Remove) != 0 || len(plan.Unmanaged) != 1 {
+		t.Errorf("Sync() = %+v, want the rule unmanaged", plan)
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.csv":          "p, alice, data, read\n",
+		"nested/b.json":  `{"g": [["alice", "admin"]], "p": [["alice", "data", "read"]]}`,
+		"nested/c.yml":   "p:\n- [bob, data, write]\n",
+		"README.md":      "not a policy",
+		"nested/.hidden": "p, nobody, data, read\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	set, err := ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := NewSet([]Rule{rule("p", "alice", "data", "read"), rule("p", "bob", "data", "write"), rule("g", "alice", "admin")})
+	if !reflect.DeepEqual(set.Rules(), want.Rules()) {
+		t.Errorf("ReadDir() = %v, want %v", set.Rules(), want.Rules())
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "bad.csv"), []byte("x, alice\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadDir(dir); err == nil {
+		t.Error("ReadDir() of a bad file succeeded")
+	}
+}
@@ -7,45 +7,113 @@ import (
 	"github.com/casbin/casbin/v2"
 	gormadapter "github.com/casbin/gorm-adapter/v3"
 	accesspb "github.com/piyush1104/access/pkg/internal"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"os"
+	"time"
 )
 
-func (server *Server) getAdapter() (*gormadapter.Adapter, error) {
+const (
+	// databaseName is the database the gorm adapter creates for casbin_rule
+	databaseName = "casbin"
+	// modelPath is the default model
+	modelPath = "pkg/casbin/auth_model.conf"
+)
+
+func datasource() string {
 	username := os.Getenv("CASBIN_DATABASE_USER")
 	password := os.Getenv("CASBIN_DATABASE_PASSWORD")
 	host := os.Getenv("CASBIN_DATABASE_HOST")
-	datasource := username + ":" + password + "@tcp(" + host + ")/"
-	return gormadapter.NewAdapter("mysql", datasource) // Your driver and data source.
+	return username + ":" + password + "@tcp(" + host + ")/"
+}
 
+// getAdapter reads casbin_rule through the pool of the server, which
+// openDatabase created the table in
+func (server *Server) getAdapter(ctx context.Context) (*gormadapter.Adapter, error) {
+	return gormadapter.NewAdapterByDB(gormadapter.TurnOffAutoMigrate(server.db.WithContext(ctx)))
 }
 
-// AuthorizeToken ...
-func (server *Server) AuthorizeToken(ctx context.Context, req *accesspb.AuthorizeTokenRequest) (*accesspb.AuthorizeReply, error) {
-	if !server.connected {
-		log.Errorf(ErrServerNotConnected.Error())
-		return nil, ErrServerNotConnected
+// getEnforcer creates an enforcer over the adapter with the full policy loaded
+func (server *Server) getEnforcer(ctx context.Context) (e *casbin.Enforcer, err error) {
+	ctx, span := server.startSpan(ctx, "casbin.LoadPolicy")
+	defer func() { endSpan(span, err) }()
+
+	a, err := server.getAdapter(ctx)
+	if err != nil {
+		return nil, err
 	}
-	// setup casbin auth rules
-	a, err := server.getAdapter()
+	m, err := server.newModel()
 	if err != nil {
-		return &accesspb.AuthorizeReply{
-			Authorized: false,
-		}, err
+		return nil, err
 	}
-	e, err := casbin.NewEnforcer("pkg/casbin/auth_model.conf", a)
+	e, err = casbin.NewEnforcer(m, a)
 	if err != nil {
-		return &accesspb.AuthorizeReply{
-			Authorized: false,
-		}, err
+		return nil, err
+	}
+	start := time.Now()
+	if err := e.LoadPolicy(); err != nil {
+		return nil, err
+	}
+	observeSince(policyLoadDuration, start)
+	p, g := len(e.GetPolicy()), len(e.GetGroupingPolicy())
+	policies.WithLabelValues("p").Set(float64(p))
+	policies.WithLabelValues("g").Set(float64(g))
+	span.SetAttributes(attribute.Int("casbin.policies", p), attribute.Int("casbin.role_links", g))
+	return e, nil
+}
+
+// enforce evaluates one request, timing the enforcer
+func (server *Server) enforce(ctx context.Context, e *casbin.Enforcer, subject, resource, action string) (bool, error) {
+	_, span := server.startSpan(ctx, "casbin.Enforce",
+		attribute.String("access.subject", subject),
+		attribute.String("access.resource", resource),
+		attribute.String("access.action", action))
+	defer observeSince(enforceDuration, time.Now())
+	allowed, err := e.Enforce(subject, resource, action)
+	span.SetAttributes(attribute.Bool("access.allowed", allowed))
+	endSpan(span, err)
+	return allowed, err
+}
+
+// tokenSubjects resolves a token of the given type, management tokens by
+// default, to the policy subjects it stands for
+func (server *Server) tokenSubjects(ctx context.Context, tokenType, token string) ([]string, error) {
+	id, err := server.resolveToken(ctx, tokenType, token)
+	if err != nil {
+		return nil, err
 	}
+	return id.Subjects, nil
+}
 
-	err = e.LoadPolicy()
+// resolveToken resolves a token of the given type, management tokens by
+// default, to the identity it belongs to
+func (server *Server) resolveToken(ctx context.Context, tokenType, token string) (*identity, error) {
+	if tokenType == "" {
+		tokenType = managementTokenType
+	}
+	resolver, ok := server.resolvers[tokenType]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown token type %q", tokenType)
+	}
+	ctx, span := server.startSpan(ctx, "access.ResolveToken", attribute.String("access.token_type", tokenType))
+	start := time.Now()
+	id, err := resolver.resolve(ctx, token)
+	observeSince(tokenValidationDuration.WithLabelValues(tokenType), start)
+	endSpan(span, err)
 	if err != nil {
-		return &accesspb.AuthorizeReply{
-			Authorized: false,
-		}, err
+		tokenValidationFailures.WithLabelValues(tokenType, tokenFailureReason(err)).Inc()
+		return nil, err
 	}
+	return id, nil
+}
 
+// AuthorizeToken ...
+func (server *Server) AuthorizeToken(ctx context.Context, req *accesspb.AuthorizeTokenRequest) (*accesspb.AuthorizeReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
 	token := req.GetToken()
 	if token == "" {
 		return &accesspb.AuthorizeReply{
@@ -67,28 +135,44 @@ func (server *Server) AuthorizeToken(ctx context.Context, req *accesspb.Authoriz
 		}, errors.New("action field is required")
 	}
 
-	res, err := server.auth.ValidateManagementToken(ctx, token)
-	if err != nil {
+	if err := server.checkResource(ctx, resource, action); err != nil {
 		return &accesspb.AuthorizeReply{
 			Authorized: false,
 		}, err
 	}
 
-	customer := res.CustomerID
-	user := res.UserID
-
-	subject := user + "_" + customer
-
-	logger.Println(subject)
+	id, err := server.resolveToken(ctx, req.GetTokenType(), token)
+	if err != nil {
+		return &accesspb.AuthorizeReply{
+			Authorized: false,
+		}, err
+	}
 
-	allowed, err := e.Enforce(subject, resource, action)
-	// ok, reason, err := e.EnforceEx(subject, "data", "read")
+	// setup casbin auth rules, those of the customer tenant with tenants enabled
+	e, err := server.decisionEnforcer(ctx, resource, id.Customer)
 	if err != nil {
 		return &accesspb.AuthorizeReply{
 			Authorized: false,
 		}, err
 	}
 
+	// any subject the token stands for may grant the access
+	allowed := false
+	for _, subject := range id.Subjects {
+		allowed, err = server.enforce(ctx, e, subject, resource, action)
+		// ok, reason, err := e.EnforceEx(subject, "data", "read")
+		if err != nil {
+			server.recordDecision("AuthorizeToken", resource, action, false, err)
+			return &accesspb.AuthorizeReply{
+				Authorized: false,
+			}, err
+		}
+		if allowed {
+			break
+		}
+	}
+	server.recordDecision("AuthorizeToken", resource, action, allowed, nil)
+
 	// logger.Println(ok, reason)
 
 	return &accesspb.AuthorizeReply{
@@ -98,31 +182,10 @@ func (server *Server) AuthorizeToken(ctx context.Context, req *accesspb.Authoriz
 
 // AuthorizeToken ...
 func (server *Server) Authorize(ctx context.Context, req *accesspb.AuthorizeRequest) (*accesspb.AuthorizeReply, error) {
-	if !server.connected {
+	if !server.isConnected() {
 		log.Errorf(ErrServerNotConnected.Error())
 		return nil, ErrServerNotConnected
 	}
-	// setup casbin auth rules
-	a, err := server.getAdapter()
-	if err != nil {
-		return &accesspb.AuthorizeReply{
-			Authorized: false,
-		}, err
-	}
-	e, err := casbin.NewEnforcer("cmd/auth_model.conf", a)
-	if err != nil {
-		return &accesspb.AuthorizeReply{
-			Authorized: false,
-		}, err
-	}
-
-	err = e.LoadPolicy()
-	if err != nil {
-		return &accesspb.AuthorizeReply{
-			Authorized: false,
-		}, err
-	}
-
 	subject := req.GetSubject()
 	if subject == "" {
 		return &accesspb.AuthorizeReply{
@@ -144,10 +207,25 @@ func (server *Server) Authorize(ctx context.Context, req *accesspb.AuthorizeRequ
 		}, errors.New("action field is required")
 	}
 
+	if err := server.checkResource(ctx, resource, action); err != nil {
+		return &accesspb.AuthorizeReply{
+			Authorized: false,
+		}, err
+	}
+
+	// setup casbin auth rules
+	e, err := server.decisionEnforcer(ctx, resource, "")
+	if err != nil {
+		return &accesspb.AuthorizeReply{
+			Authorized: false,
+		}, err
+	}
+
 	logger.Println(subject)
 
-	allowed, err := e.Enforce(subject, resource, action)
+	allowed, err := server.enforce(ctx, e, subject, resource, action)
 	// ok, reason, err := e.EnforceEx(subject, "data", "read")
+	server.recordDecision("Authorize", resource, action, allowed, err)
 	if err != nil {
 		return &accesspb.AuthorizeReply{
 			Authorized: false,
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	grpc_auth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
+	"github.com/piyush1104/access/pkg/policy"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// adminNamespace prefixes the objects guarding the RPCs of the service
+	// itself, e.g. access:rpc/ApproveAccess, keeping them apart from the
+	// resources of the policies it serves
+	adminNamespace = "access:rpc/"
+	// adminAction is the action a principal needs on an admin object
+	adminAction = policy.AdminAction
+	// requestForOthers is the admin object allowing access requests for
+	// subjects the caller did not authenticate as
+	requestForOthers = "RequestAccess/others"
+	// tokenTypeHeader selects the resolver of the bearer token, management
+	// tokens being assumed without it
+	tokenTypeHeader = "x-access-token-type"
+	// adminRefresh is how long admin grants changed through other servers
+	// take to be seen
+	adminRefresh = 10 * time.Second
+)
+
+// AdminConfig protects RPCs with grants in the admin namespace of the
+// enforcer, e.g. p, ops, access:rpc/ApproveAccess, call
+type AdminConfig struct {
+	Enabled bool `mapstructure:"enabled,omitempty"`
+	// RPCs are the method names needing a grant, every other RPC staying
+	// open. "*" protects every RPC.
+	RPCs []string `mapstructure:"rpcs,omitempty"`
+	// Superuser is allowed every RPC without a grant, bootstrapping the
+	// grants of everyone else
+	Superuser string `mapstructure:"superuser,omitempty"`
+}
+
+// adminGate authenticates callers of protected RPCs by the subjects of their
+// bearer token and the identity of their client certificate, and authorizes
+// them against the admin namespace
+type adminGate struct {
+	server *Server
+	rpcs   map[string]bool
+}
+
+func newAdminGate(server *Server, config *AdminConfig) *adminGate {
+	rpcs := make(map[string]bool, len(config.RPCs))
+	for _, rpc := range config.RPCs {
+		rpcs[rpc] = true
+	}
+	return &adminGate{server: server, rpcs: rpcs}
+}
+
+func (g *adminGate) protected(fullMethod string) bool {
+	if strings.HasPrefix(fullMethod, "/grpc.health.v1.Health/") {
+		return false
+	}
+	return g.rpcs[anyCaller] || g.rpcs[fullMethod[strings.LastIndex(fullMethod, "/")+1:]]
+}
+
+// principals are the subjects the caller authenticated as: the identity of
+// its client certificate and the subjects of its bearer token
+func (server *Server) principals(ctx context.Context) ([]string, error) {
+	var subjects []string
+	if caller := callerIdentity(ctx); caller != "" {
+		subjects = append(subjects, caller)
+	}
+	token, err := grpc_auth.AuthFromMD(ctx, "bearer")
+	if err != nil {
+		if len(subjects) > 0 {
+			return subjects, nil
+		}
+		return nil, ErrAdminUnauthenticated
+	}
+	var tokenType string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(tokenTypeHeader); len(values) > 0 {
+			tokenType = values[0]
+		}
+	}
+	tokenSubjects, err := server.tokenSubjects(ctx, tokenType, token)
+	if err != nil {
+		return nil, err
+	}
+	return append(subjects, tokenSubjects...), nil
+}
+
+// check fails callers without a grant on fullMethod when it is protected
+func (g *adminGate) check(ctx context.Context, fullMethod string) error {
+	if !g.protected(fullMethod) {
+		return nil
+	}
+	principals, err := g.server.principals(ctx)
+	if err != nil {
+		return err
+	}
+	allowed, err := g.server.adminAllowed(ctx, principals, fullMethod[strings.LastIndex(fullMethod, "/")+1:])
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		logger.Printf("Error!!!%v may not call %s", principals, fullMethod)
+		return ErrAdminNotAllowed
+	}
+	return nil
+}
+
+// adminAllowed tells whether any of principals is the superuser or has a
+// grant on rpc in the admin namespace
+func (server *Server) adminAllowed(ctx context.Context, principals []string, rpc string) (bool, error) {
+	superuser := server.config.Admin.Superuser
+	for _, principal := range principals {
+		if superuser != "" && principal == superuser {
+			return true, nil
+		}
+	}
+
+	e, err := server.adminPolicy.get(ctx, server)
+	if err != nil {
+		return false, err
+	}
+	for _, principal := range principals {
+		allowed, err := server.enforce(ctx, e, principal, adminNamespace+rpc, adminAction)
+		if err != nil || allowed {
+			return allowed, err
+		}
+	}
+	return false, nil
+}
+
+func (g *adminGate) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := g.check(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (g *adminGate) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := g.check(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// adminPolicy caches the enforcer of admin grants, holding only the rules in
+// the admin namespace and the role links, so checking a call does not load
+// the whole policy
+type adminPolicy struct {
+	mu     sync.Mutex
+	e      *casbin.Enforcer
+	loaded time.Time
+}
+
+// get returns the cached enforcer, loading it again once older than
+// adminRefresh. A failed load keeps the last one in use.
+func (p *adminPolicy) get(ctx context.Context, server *Server) (*casbin.Enforcer, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.e != nil && time.Since(p.loaded) < adminRefresh {
+		return p.e, nil
+	}
+	e, _, err := server.loadTenant(ctx, adminNamespace, policy.Filter{Prefix: adminNamespace})
+	if err != nil {
+		if p.e == nil {
+			return nil, err
+		}
+		logger.Println("Error!!!Failed to reload admin grants:", err)
+		return p.e, nil
+	}
+	p.e, p.loaded = e, time.Now()
+	return e, nil
+}
+
+// reset makes the next check load the admin grants again
+func (p *adminPolicy) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.e = nil
+}
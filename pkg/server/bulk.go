@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+
+	"github.com/100mslive/packages/log"
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	accesspb "github.com/piyush1104/access/pkg/internal"
+	"github.com/piyush1104/access/pkg/policy"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxBulkRules bounds the rules of one bulk request
+const maxBulkRules = 10000
+
+// AddPolicies adds p rules and g links all at once, or none of them when any
+// is invalid, skipping the ones the store has
+func (server *Server) AddPolicies(ctx context.Context, req *accesspb.BulkPolicyRequest) (*accesspb.BulkPolicyReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+	if len(req.GetRules()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "rules field is required")
+	}
+	rules, errs, err := server.bulkRules(ctx, req.GetRules(), true)
+	if err != nil || len(errs) > 0 {
+		return &accesspb.BulkPolicyReply{Errors: errs}, err
+	}
+	return server.bulkApply(ctx, rules, func(current policy.Set) *policy.Diff {
+		return policy.Merge(current, policy.NewSet(rules))
+	})
+}
+
+// RemovePolicies removes p rules and g links all at once, or none of them
+// when any is invalid, skipping the ones the store does not have
+func (server *Server) RemovePolicies(ctx context.Context, req *accesspb.BulkPolicyRequest) (*accesspb.BulkPolicyReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+	if len(req.GetRules()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "rules field is required")
+	}
+	rules, errs, err := server.bulkRules(ctx, req.GetRules(), false)
+	if err != nil || len(errs) > 0 {
+		return &accesspb.BulkPolicyReply{Errors: errs}, err
+	}
+	return server.bulkApply(ctx, nil, func(current policy.Set) *policy.Diff {
+		return policy.Subtract(current, policy.NewSet(rules))
+	})
+}
+
+// ReplacePolicies makes the rules of the subjects, the p rules and g links
+// they are the first value of, exactly the requested ones, all at once
+func (server *Server) ReplacePolicies(ctx context.Context, req *accesspb.ReplacePoliciesRequest) (*accesspb.BulkPolicyReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+	if len(req.GetSubjects()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "subjects field is required")
+	}
+	subjects := make(map[string]bool, len(req.GetSubjects()))
+	for _, subject := range req.GetSubjects() {
+		if subject == "" {
+			return nil, status.Error(codes.InvalidArgument, "subjects must not be empty")
+		}
+		subjects[subject] = true
+	}
+	rules, errs, err := server.bulkRules(ctx, req.GetRules(), true)
+	if err != nil {
+		return nil, err
+	}
+	for i, rule := range req.GetRules() {
+		if values := rule.GetValues(); len(values) > 0 && !subjects[values[0]] {
+			errs = append(errs, &accesspb.RuleError{Index: int32(i), Message: "subject " + values[0] + " is not replaced"})
+		}
+	}
+	if len(errs) > 0 {
+		return &accesspb.BulkPolicyReply{Errors: errs}, nil
+	}
+	return server.bulkApply(ctx, rules, func(current policy.Set) *policy.Diff {
+		var owned []policy.Rule
+		for _, rule := range current.Rules() {
+			if len(rule.Values) > 0 && subjects[rule.Values[0]] {
+				owned = append(owned, rule)
+			}
+		}
+		return policy.Replace(policy.NewSet(owned), policy.NewSet(rules))
+	})
+}
+
+// bulkRules checks every rule against the model and, for rules to add, the
+// resource registry, returning the errors by index of the invalid ones
+func (server *Server) bulkRules(ctx context.Context, in []*accesspb.PolicyRule, adding bool) ([]policy.Rule, []*accesspb.RuleError, error) {
+	if len(in) > maxBulkRules {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "more than %d rules", maxBulkRules)
+	}
+	m, err := server.newModel()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var errs []*accesspb.RuleError
+	rules := make([]policy.Rule, 0, len(in))
+	for i, r := range in {
+		rule := policy.Rule{PType: r.GetPType(), Values: r.GetValues()}
+		if err := policy.CheckRule(m, rule); err != nil {
+			errs = append(errs, &accesspb.RuleError{Index: int32(i), Message: err.Error()})
+			continue
+		}
+		if adding {
+			if err := server.checkRules(ctx, []policy.Rule{rule}); err != nil {
+				if status.Code(err) != codes.InvalidArgument {
+					return nil, nil, err
+				}
+				errs = append(errs, &accesspb.RuleError{Index: int32(i), Message: status.Convert(err).Message()})
+				continue
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, errs, nil
+}
+
+// bulkApply changes the store by the diff of its current policy, through the
+// gorm adapter in one transaction holding the rows read. The kept rules become
+// permanent, just-in-time grants of them no longer expiring.
+func (server *Server) bulkApply(ctx context.Context, kept []policy.Rule, change func(current policy.Set) *policy.Diff) (*accesspb.BulkPolicyReply, error) {
+	var diff *policy.Diff
+	err := server.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := policy.KeepGrants(tx, kept); err != nil {
+			return err
+		}
+		current, err := policy.Load(tx.Clauses(clause.Locking{Strength: "UPDATE"}))
+		if err != nil {
+			return err
+		}
+		diff = change(current)
+		if diff.Empty() {
+			return nil
+		}
+		a, err := txAdapter(tx)
+		if err != nil {
+			return err
+		}
+		for ptype, rows := range byPType(diff.Remove) {
+			if err := a.RemovePolicies(ptype[:1], ptype, rows); err != nil {
+				return err
+			}
+		}
+		for ptype, rows := range byPType(diff.Add) {
+			if err := a.AddPolicies(ptype[:1], ptype, rows); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !diff.Empty() {
+		server.policyChanged(append(diff.Add, diff.Remove...)...)
+	}
+	return &accesspb.BulkPolicyReply{
+		Added:   policyRules(diff.Add),
+		Removed: policyRules(diff.Remove),
+		Applied: !diff.Empty(),
+	}, nil
+}
+
+// txAdapter writes casbin_rule through the gorm adapter within tx
+func txAdapter(tx *gorm.DB) (*gormadapter.Adapter, error) {
+	return gormadapter.NewAdapterByDB(gormadapter.TurnOffAutoMigrate(tx))
+}
+
+func byPType(rules []policy.Rule) map[string][][]string {
+	out := make(map[string][][]string)
+	for _, rule := range rules {
+		out[rule.PType] = append(out[rule.PType], rule.Values)
+	}
+	return out
+}
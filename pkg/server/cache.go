@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type tokenCacheEntry struct {
+	identity *tokenIdentity
+	err      error
+	expires  time.Time
+}
+
+// cachingValidator remembers validation results by token hash. Valid tokens
+// are kept until their exp claim or maxTTL, whichever comes first, and tokens
+// rejected as invalid for negativeTTL. Failures reaching the validator are
+// never cached. While disabled every token goes to the next validator.
+type cachingValidator struct {
+	next        tokenValidator
+	maxTTL      time.Duration
+	negativeTTL time.Duration
+	size        int
+	enabled     int32
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]tokenCacheEntry
+}
+
+func newCachingValidator(next tokenValidator, config *TokenConfig) *cachingValidator {
+	return &cachingValidator{
+		next:        next,
+		maxTTL:      time.Duration(config.CacheTTL) * time.Second,
+		negativeTTL: time.Duration(config.NegativeCacheTTL) * time.Second,
+		size:        config.CacheSize,
+		entries:     make(map[[sha256.Size]byte]tokenCacheEntry),
+	}
+}
+
+// setEnabled turns caching on or off, dropping the cached results
+func (v *cachingValidator) setEnabled(enabled bool) {
+	var e int32
+	if enabled {
+		e = 1
+	}
+	if atomic.SwapInt32(&v.enabled, e) == e {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.entries = make(map[[sha256.Size]byte]tokenCacheEntry)
+	tokenCacheEntries.Set(0)
+}
+
+func (v *cachingValidator) validate(ctx context.Context, token string) (*tokenIdentity, error) {
+	if atomic.LoadInt32(&v.enabled) == 0 {
+		return v.next.validate(ctx, token)
+	}
+	key := sha256.Sum256([]byte(token))
+	now := time.Now()
+
+	v.mu.Lock()
+	entry, ok := v.entries[key]
+	if ok && now.After(entry.expires) {
+		delete(v.entries, key)
+		ok = false
+	}
+	v.mu.Unlock()
+	if ok {
+		if entry.err != nil {
+			tokenCacheRequests.WithLabelValues("negative_hit").Inc()
+		} else {
+			tokenCacheRequests.WithLabelValues("hit").Inc()
+		}
+		return entry.identity, entry.err
+	}
+	tokenCacheRequests.WithLabelValues("miss").Inc()
+
+	identity, err := v.next.validate(ctx, token)
+	switch {
+	case err == nil:
+		expires := now.Add(v.maxTTL)
+		if exp := tokenExpiry(token); !exp.IsZero() && exp.Before(expires) {
+			expires = exp
+		}
+		v.store(key, tokenCacheEntry{identity: identity, expires: expires}, now)
+	case isInvalidToken(err) && v.negativeTTL > 0:
+		v.store(key, tokenCacheEntry{err: err, expires: now.Add(v.negativeTTL)}, now)
+	}
+	return identity, err
+}
+
+func (v *cachingValidator) store(key [sha256.Size]byte, entry tokenCacheEntry, now time.Time) {
+	if !entry.expires.After(now) {
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(v.entries) >= v.size {
+		for k, e := range v.entries {
+			if now.After(e.expires) {
+				delete(v.entries, k)
+			}
+		}
+	}
+	// still full of live entries, drop any
+	for k := range v.entries {
+		if len(v.entries) < v.size {
+			break
+		}
+		delete(v.entries, k)
+	}
+	v.entries[key] = entry
+	tokenCacheEntries.Set(float64(len(v.entries)))
+}
+
+// tokenExpiry reads the exp claim without verifying the token, which the
+// validator has already done
+func tokenExpiry(token string) time.Time {
+	claims := &jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil || claims.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return claims.ExpiresAt.Time
+}
+
+// isInvalidToken tells a rejected token apart from a failure to validate it
+func isInvalidToken(err error) bool {
+	var verr *jwt.ValidationError
+	if errors.As(err, &verr) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.Unauthenticated, codes.PermissionDenied, codes.NotFound:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// ClientAuthNone does not ask callers for a certificate
+	ClientAuthNone = ""
+	// ClientAuthOptional verifies a certificate when the caller presents one
+	ClientAuthOptional = "optional"
+	// ClientAuthRequire rejects callers without a certificate signed by ClientCA
+	ClientAuthRequire = "require"
+
+	// anyCaller matches every caller, with or without a certificate, in
+	// CallerConfig.Identity, and every RPC in CallerConfig.RPCs
+	anyCaller = "*"
+)
+
+// CallerConfig allows a caller, identified by its client certificate, to use
+// some RPCs
+type CallerConfig struct {
+	// Identity is the first URI SAN of the caller certificate, e.g. a SPIFFE
+	// id, or its common name when it has none. "*" matches every caller.
+	Identity string `mapstructure:"identity,omitempty"`
+	// RPCs are the method names, e.g. AuthorizeToken, the caller may use.
+	// "*" allows every RPC.
+	RPCs []string `mapstructure:"rpcs,omitempty"`
+}
+
+// callerIdentity is the identity of the verified client certificate of the
+// peer, empty for callers without one
+func callerIdentity(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	cert := info.State.VerifiedChains[0][0]
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String()
+	}
+	return cert.Subject.CommonName
+}
+
+// serverTLSConfig loads the server certificate and, with ClientAuth set, the
+// CA bundle verifying client certificates
+func serverTLSConfig(config *Config) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(config.Cert, config.Key)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2"},
+	}
+	switch config.ClientAuth {
+	case ClientAuthNone:
+		return tlsConfig, nil
+	case ClientAuthOptional:
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown client auth %q", config.ClientAuth)
+	}
+	if config.ClientCA == "" {
+		return nil, fmt.Errorf("client_ca is required by client auth %q", config.ClientAuth)
+	}
+	pem, err := os.ReadFile(config.ClientCA)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s: no certificates found", config.ClientCA)
+	}
+	tlsConfig.ClientCAs = pool
+	return tlsConfig, nil
+}
+
+// callerGate allows callers the RPCs configured for them. Without configured
+// callers every caller may use every RPC; health checks are always allowed.
+type callerGate struct {
+	rpcs map[string]map[string]bool
+}
+
+func newCallerGate(callers []CallerConfig) (*callerGate, error) {
+	if len(callers) == 0 {
+		return &callerGate{}, nil
+	}
+	g := &callerGate{rpcs: make(map[string]map[string]bool)}
+	for i, caller := range callers {
+		if caller.Identity == "" {
+			return nil, fmt.Errorf("caller %d: identity is required", i)
+		}
+		if _, ok := g.rpcs[caller.Identity]; ok {
+			return nil, fmt.Errorf("caller %s: duplicate identity", caller.Identity)
+		}
+		rpcs := make(map[string]bool, len(caller.RPCs))
+		for _, rpc := range caller.RPCs {
+			rpcs[rpc] = true
+		}
+		g.rpcs[caller.Identity] = rpcs
+	}
+	return g, nil
+}
+
+func (g *callerGate) allowed(caller, fullMethod string) bool {
+	if g.rpcs == nil || strings.HasPrefix(fullMethod, "/grpc.health.v1.Health/") {
+		return true
+	}
+	rpc := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	for _, identity := range []string{caller, anyCaller} {
+		if rpcs, ok := g.rpcs[identity]; ok && (rpcs[anyCaller] || rpcs[rpc]) {
+			return true
+		}
+	}
+	return false
+}
+
+// check fails callers not allowed fullMethod
+func (g *callerGate) check(ctx context.Context, fullMethod string) error {
+	caller := callerIdentity(ctx)
+	if g.allowed(caller, fullMethod) {
+		return nil
+	}
+	if caller == "" {
+		return ErrCallerUnauthenticated
+	}
+	logger.Printf("Error!!!Caller %s may not call %s", caller, fullMethod)
+	return ErrCallerNotAllowed
+}
+
+func (g *callerGate) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := g.check(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (g *callerGate) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := g.check(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
@@ -13,4 +13,57 @@ func (config *Config) SetDefaults() {
 	} else if config.Metrics == -1 {
 		config.Metrics = 0
 	}
+	if config.Model == "" {
+		config.Model = d.Model
+	}
+	if config.MetricsLabelLimit == 0 {
+		config.MetricsLabelLimit = d.MetricsLabelLimit
+	}
+	if config.Limits.Callers == 0 {
+		config.Limits.Callers = d.Limits.Callers
+	}
+	if config.Limits.Burst == 0 {
+		config.Limits.Burst = int(config.Limits.Rate)
+		if config.Limits.Burst < 1 {
+			config.Limits.Burst = 1
+		}
+	}
+	if config.Limits.MinConcurrent == 0 {
+		config.Limits.MinConcurrent = d.Limits.MinConcurrent
+	}
+	if len(config.Admin.RPCs) == 0 {
+		config.Admin.RPCs = d.Admin.RPCs
+	}
+	if config.Token.Mode == "" {
+		config.Token.Mode = d.Token.Mode
+	}
+	if len(config.Token.Subjects) == 0 {
+		config.Token.Subjects = d.Token.Subjects
+	}
+	if config.Token.CacheTTL == 0 {
+		config.Token.CacheTTL = d.Token.CacheTTL
+	}
+	if config.Token.NegativeCacheTTL == 0 {
+		config.Token.NegativeCacheTTL = d.Token.NegativeCacheTTL
+	} else if config.Token.NegativeCacheTTL == -1 {
+		config.Token.NegativeCacheTTL = 0
+	}
+	if config.Token.CacheSize == 0 {
+		config.Token.CacheSize = d.Token.CacheSize
+	}
+	if config.Tenants.Separator == "" {
+		config.Tenants.Separator = d.Tenants.Separator
+	}
+	if config.Tenants.Depth == 0 {
+		config.Tenants.Depth = d.Tenants.Depth
+	}
+	if config.Tenants.Domain == "" {
+		config.Tenants.Domain = d.Tenants.Domain
+	}
+	if config.Tenants.Size == 0 {
+		config.Tenants.Size = d.Tenants.Size
+	}
+	if config.Tenants.TTL == 0 {
+		config.Tenants.TTL = d.Tenants.TTL
+	}
 }
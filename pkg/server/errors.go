@@ -1,8 +1,35 @@
 package server
 
-import "errors"
+import (
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
 
 var (
 	//ErrServerNotConnected ...
 	ErrServerNotConnected = errors.New("Error: server not connected")
+	//ErrAccessRequestNotFound ...
+	ErrAccessRequestNotFound = status.Error(codes.NotFound, "access request not found")
+	//ErrAccessRequestDecided ...
+	ErrAccessRequestDecided = status.Error(codes.FailedPrecondition, "access request is not pending")
+	//ErrApproverNotAllowed ...
+	ErrApproverNotAllowed = status.Error(codes.PermissionDenied, "approver may not decide this access request")
+	//ErrRequesterNotAllowed ...
+	ErrRequesterNotAllowed = status.Error(codes.PermissionDenied, "caller may not request access for this subject")
+	//ErrCallerUnauthenticated ...
+	ErrCallerUnauthenticated = status.Error(codes.Unauthenticated, "client certificate required")
+	//ErrCallerNotAllowed ...
+	ErrCallerNotAllowed = status.Error(codes.PermissionDenied, "caller may not use this rpc")
+	//ErrAdminUnauthenticated ...
+	ErrAdminUnauthenticated = status.Error(codes.Unauthenticated, "bearer token or client certificate required")
+	//ErrAdminNotAllowed ...
+	ErrAdminNotAllowed = status.Error(codes.PermissionDenied, "caller has no grant on this rpc")
+	//ErrRateLimited ...
+	ErrRateLimited = status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	//ErrOverloaded ...
+	ErrOverloaded = status.Error(codes.ResourceExhausted, "server overloaded")
+	//ErrResourceTypeStatic ...
+	ErrResourceTypeStatic = status.Error(codes.FailedPrecondition, "resource type is defined in the config")
 )
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const probeTimeout = 2 * time.Second
+
+var errNotProbed = errors.New("not probed yet")
+
+// dependency is something the server needs to serve, reported under its own
+// health service name
+type dependency struct {
+	service string
+	check   func(ctx context.Context) error
+}
+
+func (server *Server) dependencies() []dependency {
+	deps := []dependency{{
+		service: server.service + ".database",
+		check: func(ctx context.Context) error {
+			if server.db == nil {
+				return ErrDatabaseNotReady
+			}
+			sqlDB, err := server.db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		},
+	}}
+	if server.config.Token.Mode != TokenModeLocal && server.auth != nil {
+		deps = append(deps, dependency{
+			service: server.service + ".auth",
+			check:   server.auth.Ping,
+		})
+	}
+	return deps
+}
+
+// probe checks every dependency and publishes its status
+func (server *Server) probe() {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	probes := make(map[string]error)
+	for _, dep := range server.dependencies() {
+		err := dep.check(ctx)
+		status := grpc_health_v1.HealthCheckResponse_SERVING
+		if err != nil {
+			logger.Printf("Error!!!%s is not healthy: %v", dep.service, err)
+			status = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+		}
+		probes[dep.service] = err
+		server.health.SetServingStatus(dep.service, status)
+	}
+
+	server.probesMu.Lock()
+	server.probes = probes
+	server.probesMu.Unlock()
+}
+
+func (server *Server) dependencyError(service string) error {
+	server.probesMu.RLock()
+	defer server.probesMu.RUnlock()
+	err, ok := server.probes[service]
+	if !ok {
+		return errNotProbed
+	}
+	return err
+}
+
+// serveLiveness reports whether the process is up
+func (server *Server) serveLiveness(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok\n"))
+}
+
+// serveReadiness reports whether the server and each dependency can serve,
+// with 503 if any cannot
+func (server *Server) serveReadiness(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{server.service: "ok"}
+	code := http.StatusOK
+	if !server.isConnected() {
+		checks[server.service] = ErrServerNotConnected.Error()
+		code = http.StatusServiceUnavailable
+	}
+	for _, dep := range server.dependencies() {
+		checks[dep.service] = "ok"
+		if err := server.dependencyError(dep.service); err != nil {
+			checks[dep.service] = err.Error()
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(checks)
+}
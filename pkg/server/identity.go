@@ -0,0 +1,299 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ResolverJWT resolves generic OIDC style JWTs
+	ResolverJWT = "jwt"
+	// ResolverAPIKey resolves static API keys listed in a file
+	ResolverAPIKey = "api_key"
+)
+
+// ResolverConfig configures the identity resolver of one token type
+type ResolverConfig struct {
+	// TokenType is the value of the request token type selecting the resolver
+	TokenType string `mapstructure:"token_type,omitempty"`
+	// Kind is jwt or api_key
+	Kind string `mapstructure:"kind,omitempty"`
+
+	// Secret, PublicKey and JWKS verify jwt signatures as in TokenConfig
+	Secret    string `mapstructure:"secret,omitempty"`
+	PublicKey string `mapstructure:"public_key,omitempty"`
+	JWKS      string `mapstructure:"jwks,omitempty"`
+	Issuer    string `mapstructure:"issuer,omitempty"`
+	Audience  string `mapstructure:"audience,omitempty"`
+	// SubjectClaim is the claim, dotted for nested claims, holding the
+	// subject. Defaults to sub.
+	SubjectClaim string `mapstructure:"subject_claim,omitempty"`
+	// SubjectPrefix is prepended to the claim to namespace the subjects
+	SubjectPrefix string `mapstructure:"subject_prefix,omitempty"`
+	// Subjects are templates over claims, e.g. "{org}/{sub}", replacing
+	// SubjectClaim and SubjectPrefix when set
+	Subjects []string `mapstructure:"subjects,omitempty"`
+	// RolesClaim holds the roles the token declares, as a list or a space
+	// separated string
+	RolesClaim string `mapstructure:"roles_claim,omitempty"`
+	// RoleSubject is the template, over claims and {role}, of the subject
+	// each declared role maps to
+	RoleSubject string `mapstructure:"role_subject,omitempty"`
+	// CustomerClaim, dotted for nested claims, holds the customer whose
+	// tenant decides for the token when tenants are enabled
+	CustomerClaim string `mapstructure:"customer_claim,omitempty"`
+
+	// KeyFile lists api keys, one per line followed by its subjects
+	KeyFile string `mapstructure:"key_file,omitempty"`
+}
+
+// identity is who a token belongs to. Its subjects, the primary one first,
+// are all considered during enforcement. Customer, when known, selects the
+// tenant deciding for it.
+type identity struct {
+	Subjects []string
+	Customer string
+}
+
+// identityResolver turns a token into the identity policies are enforced for
+type identityResolver interface {
+	resolve(ctx context.Context, token string) (*identity, error)
+}
+
+// newIdentityResolvers builds the resolver of every token type, management
+// tokens being validated by tokens
+func newIdentityResolvers(configs []ResolverConfig, token *TokenConfig, tokens tokenValidator) (map[string]identityResolver, error) {
+	mapper, err := newSubjectMapper(token.Subjects, token.RoleSubject, managementAttributes)
+	if err != nil {
+		return nil, fmt.Errorf("management tokens: %v", err)
+	}
+	resolvers := map[string]identityResolver{
+		managementTokenType: &managementResolver{tokens: tokens, mapper: mapper},
+	}
+	for i := range configs {
+		config := &configs[i]
+		if config.TokenType == "" {
+			return nil, fmt.Errorf("resolver %d: token_type is required", i)
+		}
+		if _, ok := resolvers[config.TokenType]; ok {
+			return nil, fmt.Errorf("resolver %s: duplicate token type", config.TokenType)
+		}
+
+		var resolver identityResolver
+		var err error
+		switch config.Kind {
+		case ResolverJWT:
+			resolver, err = newJWTResolver(config)
+		case ResolverAPIKey:
+			resolver, err = newAPIKeyResolver(config)
+		default:
+			err = fmt.Errorf("unknown kind %q", config.Kind)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("resolver %s: %v", config.TokenType, err)
+		}
+		resolvers[config.TokenType] = resolver
+	}
+	return resolvers, nil
+}
+
+// managementAttributes are what subject templates of management tokens may
+// use
+var managementAttributes = []string{"user_id", "customer_id"}
+
+// managementResolver resolves 100ms management tokens, whose subject
+// templates may use {user_id} and {customer_id}
+type managementResolver struct {
+	tokens tokenValidator
+	mapper *subjectMapper
+}
+
+func (r *managementResolver) resolve(ctx context.Context, token string) (*identity, error) {
+	res, err := r.tokens.validate(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	subjects, err := r.mapper.apply(func(name string) (string, bool) {
+		switch name {
+		case "user_id":
+			return res.UserID, true
+		case "customer_id":
+			return res.CustomerID, true
+		}
+		return "", false
+	}, res.Roles)
+	if err != nil {
+		return nil, err
+	}
+	return &identity{Subjects: subjects, Customer: res.CustomerID}, nil
+}
+
+// jwtResolver resolves JWTs from other issuers, mapping claims to subjects
+type jwtResolver struct {
+	keys     *jwtKeys
+	issuer   string
+	audience string
+	mapper   *subjectMapper
+	roles    []string
+	customer []string
+}
+
+func newJWTResolver(config *ResolverConfig) (*jwtResolver, error) {
+	keys, err := newJWTKeys(config.Secret, config.PublicKey, config.JWKS)
+	if err != nil {
+		return nil, err
+	}
+	subjects := config.Subjects
+	if len(subjects) == 0 {
+		claim := config.SubjectClaim
+		if claim == "" {
+			claim = "sub"
+		}
+		subjects = []string{config.SubjectPrefix + "{" + claim + "}"}
+	}
+	// claims vary by issuer, any may be used
+	mapper, err := newSubjectMapper(subjects, config.RoleSubject, nil)
+	if err != nil {
+		return nil, err
+	}
+	r := &jwtResolver{
+		keys:     keys,
+		issuer:   config.Issuer,
+		audience: config.Audience,
+		mapper:   mapper,
+	}
+	if config.RolesClaim != "" {
+		r.roles = strings.Split(config.RolesClaim, ".")
+	}
+	if config.CustomerClaim != "" {
+		r.customer = strings.Split(config.CustomerClaim, ".")
+	}
+	return r, nil
+}
+
+func (r *jwtResolver) resolve(ctx context.Context, token string) (*identity, error) {
+	claims := jwt.MapClaims{}
+	if err := r.keys.parse(token, claims); err != nil {
+		return nil, err
+	}
+	if r.issuer != "" && !claims.VerifyIssuer(r.issuer, true) {
+		return nil, status.Error(codes.Unauthenticated, "token issuer mismatch")
+	}
+	if r.audience != "" && !claims.VerifyAudience(r.audience, true) {
+		return nil, status.Error(codes.Unauthenticated, "token audience mismatch")
+	}
+
+	var roles []string
+	if r.roles != nil {
+		roles = claimStrings(claims, r.roles)
+	}
+	subjects, err := r.mapper.apply(func(name string) (string, bool) {
+		return claimString(claims, strings.Split(name, "."))
+	}, roles)
+	if err != nil {
+		return nil, err
+	}
+	id := &identity{Subjects: subjects}
+	if r.customer != nil {
+		id.Customer, _ = claimString(claims, r.customer)
+	}
+	return id, nil
+}
+
+func claimValue(claims map[string]interface{}, path []string) (interface{}, bool) {
+	var value interface{} = claims
+	for _, name := range path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[name]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// claimStrings looks up a list claim, or a space separated string claim
+func claimStrings(claims map[string]interface{}, path []string) []string {
+	value, _ := claimValue(claims, path)
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// claimString looks up a string or numeric claim by path
+func claimString(claims map[string]interface{}, path []string) (string, bool) {
+	value, ok := claimValue(claims, path)
+	if !ok {
+		return "", false
+	}
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// apiKeyResolver resolves static api keys, held by hash
+type apiKeyResolver struct {
+	subjects map[[sha256.Size]byte][]string
+}
+
+func newAPIKeyResolver(config *ResolverConfig) (*apiKeyResolver, error) {
+	if config.KeyFile == "" {
+		return nil, fmt.Errorf("key_file is required")
+	}
+	file, err := os.Open(config.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := &apiKeyResolver{subjects: make(map[[sha256.Size]byte][]string)}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"key subject...\"", config.KeyFile, line)
+		}
+		r.subjects[sha256.Sum256([]byte(fields[0]))] = fields[1:]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *apiKeyResolver) resolve(ctx context.Context, token string) (*identity, error) {
+	subjects, ok := r.subjects[sha256.Sum256([]byte(token))]
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "unknown api key")
+	}
+	return &identity{Subjects: subjects}, nil
+}
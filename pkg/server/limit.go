@@ -0,0 +1,223 @@
+package server
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+// LimitConfig protects the server from callers sending more than it can
+// enforce. Every limit is off when zero.
+type LimitConfig struct {
+	// Rate is the sustained calls per second allowed to each caller, keyed by
+	// client certificate identity or, without one, peer address
+	Rate float64 `mapstructure:"rate,omitempty"`
+	// Burst is the calls a caller may make at once above Rate
+	Burst int `mapstructure:"burst,omitempty"`
+	// Callers bounds the callers tracked, idle ones being forgotten first
+	Callers int `mapstructure:"callers,omitempty"`
+	// MaxConcurrent caps the calls in flight across all callers
+	MaxConcurrent int `mapstructure:"max_concurrent,omitempty"`
+	// TargetLatency, in milliseconds, enables adaptive shedding: the
+	// concurrency allowed shrinks while calls take longer and grows back,
+	// up to MaxConcurrent, while they are faster
+	TargetLatency int `mapstructure:"target_latency,omitempty"`
+	// MinConcurrent is the concurrency adaptive shedding never goes below
+	MinConcurrent int `mapstructure:"min_concurrent,omitempty"`
+}
+
+// callerLimiter rate limits each caller with a token bucket
+type callerLimiter struct {
+	rate  rate.Limit
+	burst int
+	size  int
+
+	mu       sync.Mutex
+	limiters map[string]*callerBucket
+}
+
+type callerBucket struct {
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+func newCallerLimiter(config *LimitConfig) *callerLimiter {
+	return &callerLimiter{
+		rate:     rate.Limit(config.Rate),
+		burst:    config.Burst,
+		size:     config.Callers,
+		limiters: make(map[string]*callerBucket),
+	}
+}
+
+func (l *callerLimiter) allow(caller string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	bucket, ok := l.limiters[caller]
+	if !ok {
+		if len(l.limiters) >= l.size {
+			l.evict()
+		}
+		bucket = &callerBucket{limiter: rate.NewLimiter(l.rate, l.burst)}
+		l.limiters[caller] = bucket
+	}
+	bucket.seen = now
+	return bucket.limiter.AllowN(now, 1)
+}
+
+// evict forgets the caller seen least recently
+func (l *callerLimiter) evict() {
+	var oldest string
+	var seen time.Time
+	for caller, bucket := range l.limiters {
+		if oldest == "" || bucket.seen.Before(seen) {
+			oldest, seen = caller, bucket.seen
+		}
+	}
+	delete(l.limiters, oldest)
+}
+
+// shedder bounds the calls in flight. With a target latency the bound adapts:
+// it shrinks by a tenth on a call slower than the target that started after
+// the last cut, so the calls in flight during one spike cut it once, and
+// grows by one per window of calls faster than it, a window being as many
+// calls as the bound.
+type shedder struct {
+	min, max float64
+	target   time.Duration
+
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	// cut is when the bound was last cut
+	cut time.Time
+}
+
+func newShedder(config *LimitConfig) *shedder {
+	s := &shedder{
+		min:    float64(config.MinConcurrent),
+		max:    float64(config.MaxConcurrent),
+		target: time.Duration(config.TargetLatency) * time.Millisecond,
+	}
+	if s.min < 1 {
+		s.min = 1
+	}
+	if s.min > s.max {
+		s.min = s.max
+	}
+	s.limit = s.max
+	concurrencyLimit.Set(s.limit)
+	return s
+}
+
+func (s *shedder) acquire() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inflight >= int(s.limit) {
+		return false
+	}
+	s.inflight++
+	return true
+}
+
+// release ends a call admitted at start
+func (s *shedder) release(start, end time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inflight--
+	if s.target == 0 {
+		return
+	}
+	if end.Sub(start) > s.target {
+		if !start.After(s.cut) {
+			return
+		}
+		s.cut = end
+		s.limit *= 0.9
+		if s.limit < s.min {
+			s.limit = s.min
+		}
+	} else if s.limit < s.max {
+		s.limit += 1 / s.limit
+		if s.limit > s.max {
+			s.limit = s.max
+		}
+	}
+	concurrencyLimit.Set(s.limit)
+}
+
+// loadGate applies the per-caller rate limits and the concurrency limit,
+// failing calls past them with ResourceExhausted. Health checks are exempt.
+type loadGate struct {
+	callers *callerLimiter
+	shedder *shedder
+}
+
+func newLoadGate(config *LimitConfig) *loadGate {
+	g := &loadGate{}
+	if config.Rate > 0 {
+		g.callers = newCallerLimiter(config)
+	}
+	if config.MaxConcurrent > 0 {
+		g.shedder = newShedder(config)
+	}
+	return g
+}
+
+// callerKey identifies a caller for rate limiting
+func callerKey(ctx context.Context) string {
+	if caller := callerIdentity(ctx); caller != "" {
+		return caller
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host
+		}
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// enter admits a call, returning the function to call once it is done
+func (g *loadGate) enter(ctx context.Context, fullMethod string) (func(), error) {
+	if strings.HasPrefix(fullMethod, "/grpc.health.v1.Health/") {
+		return func() {}, nil
+	}
+	if g.callers != nil && !g.callers.allow(callerKey(ctx), time.Now()) {
+		shed.WithLabelValues("rate").Inc()
+		return nil, ErrRateLimited
+	}
+	if g.shedder == nil {
+		return func() {}, nil
+	}
+	if !g.shedder.acquire() {
+		shed.WithLabelValues("concurrency").Inc()
+		return nil, ErrOverloaded
+	}
+	start := time.Now()
+	return func() { g.shedder.release(start, time.Now()) }, nil
+}
+
+func (g *loadGate) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	done, err := g.enter(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return handler(ctx, req)
+}
+
+func (g *loadGate) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	done, err := g.enter(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	defer done()
+	return handler(srv, ss)
+}
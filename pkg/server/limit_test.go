@@ -0,0 +1,111 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+const (
+	fast = time.Millisecond
+	slow = time.Second
+)
+
+// fill admits calls until the shedder refuses one, returning how many it
+// admitted
+func fill(s *shedder) int {
+	n := 0
+	for s.acquire() {
+		n++
+	}
+	return n
+}
+
+// clock hands out increasing times, one call after the other
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) call(s *shedder, latency time.Duration) {
+	s.acquire()
+	start := c.tick()
+	c.now = c.now.Add(latency)
+	s.release(start, c.now)
+}
+
+func (c *clock) tick() time.Time {
+	c.now = c.now.Add(time.Millisecond)
+	return c.now
+}
+
+func TestShedderFixed(t *testing.T) {
+	s := newShedder(&LimitConfig{MaxConcurrent: 3})
+	if n := fill(s); n != 3 {
+		t.Fatalf("admitted %d calls, want 3", n)
+	}
+	// without a target latency the bound never moves
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		s.release(start, start.Add(slow))
+	}
+	if n := fill(s); n != 3 {
+		t.Errorf("admitted %d calls after slow ones, want 3", n)
+	}
+}
+
+func TestShedderCutsOncePerSpike(t *testing.T) {
+	s := newShedder(&LimitConfig{MaxConcurrent: 100, TargetLatency: 100})
+	c := &clock{now: time.Now()}
+	if n := fill(s); n != 100 {
+		t.Fatalf("admitted %d calls, want 100", n)
+	}
+	// every call in flight during the spike is slow, the first one to end
+	// cuts the bound once
+	start := c.tick()
+	for i := 0; i < 100; i++ {
+		s.release(start, start.Add(slow+time.Duration(i)))
+	}
+	if s.limit != 90 {
+		t.Fatalf("limit after one spike = %v, want 90", s.limit)
+	}
+
+	// calls started after the cut cut it again
+	c.now = start.Add(2 * slow)
+	c.call(s, slow)
+	if s.limit != 81 {
+		t.Errorf("limit after a later slow call = %v, want 81", s.limit)
+	}
+}
+
+func TestShedderBounds(t *testing.T) {
+	s := newShedder(&LimitConfig{MaxConcurrent: 10, TargetLatency: 100, MinConcurrent: 8})
+	c := &clock{now: time.Now()}
+	for i := 0; i < 10; i++ {
+		c.call(s, slow)
+	}
+	if s.limit != 8 {
+		t.Errorf("limit after slow calls = %v, want the minimum 8", s.limit)
+	}
+
+	// fast calls grow it back by about one per window, up to the maximum
+	for i := 0; i < 8; i++ {
+		c.call(s, fast)
+	}
+	if s.limit <= 8 || s.limit > 9 {
+		t.Errorf("limit after a fast window = %v, want within (8, 9]", s.limit)
+	}
+	for i := 0; i < 100; i++ {
+		c.call(s, fast)
+	}
+	if s.limit != 10 {
+		t.Errorf("limit after fast calls = %v, want the maximum 10", s.limit)
+	}
+}
+
+func TestShedderMinAboveMax(t *testing.T) {
+	s := newShedder(&LimitConfig{MaxConcurrent: 2, TargetLatency: 100, MinConcurrent: 5})
+	c := &clock{now: time.Now()}
+	c.call(s, slow)
+	if n := fill(s); n != 2 {
+		t.Errorf("admitted %d calls, want the maximum 2", n)
+	}
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	metricsNamespace = "access"
+
+	// otherLabel replaces label values past the cardinality limit
+	otherLabel = "other"
+)
+
+var (
+	decisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "decisions_total",
+		Help:      "Authorization decisions by rpc, verdict (allow, deny or error), resource and action.",
+	}, []string{"rpc", "verdict", "resource", "action"})
+	enforceDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "enforce_duration_seconds",
+		Help:      "Time the enforcer takes to evaluate one request.",
+		Buckets:   []float64{.00001, .000025, .00005, .0001, .00025, .0005, .001, .0025, .005, .01},
+	})
+	policyLoadDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "policy_load_duration_seconds",
+		Help:      "Time taken to load the policy from the store.",
+		Buckets:   prometheus.DefBuckets,
+	})
+	policies = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "policies",
+		Help:      "Rules in the last loaded policy by type: p for policies, g for role links.",
+	}, []string{"ptype"})
+	tokenValidationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "token_validation_duration_seconds",
+		Help:      "Time taken to resolve a token to its subjects, by token type.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"token_type"})
+	tokenValidationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "token_validation_failures_total",
+		Help:      "Tokens that could not be resolved, by token type and reason.",
+	}, []string{"token_type", "reason"})
+
+	shed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "shed_total",
+		Help:      "Calls rejected by reason: rate for per-caller limits, concurrency for the in-flight limit.",
+	}, []string{"reason"})
+	concurrencyLimit = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "concurrency_limit",
+		Help:      "Calls allowed in flight, adapted to the target latency when set.",
+	})
+
+	tokenCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "token_cache",
+		Name:      "requests_total",
+		Help:      "Token validations served by the cache, by result: hit, negative_hit or miss.",
+	}, []string{"result"})
+	tokenCacheEntries = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "token_cache",
+		Name:      "entries",
+		Help:      "Validation results held in the token cache.",
+	})
+
+	tenantCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "tenant_cache",
+		Name:      "requests_total",
+		Help:      "Decisions served by tenant enforcers, by result: hit, miss or expired, the last being served while the tenant reloads.",
+	}, []string{"result"})
+	tenantCacheEntries = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "tenant_cache",
+		Name:      "entries",
+		Help:      "Tenant enforcers held in the cache.",
+	})
+	tenantCacheEvictions = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "tenant_cache",
+		Name:      "evictions_total",
+		Help:      "Tenant enforcers dropped to make room, least recently used first.",
+	})
+	tenantPolicyBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "tenant_cache",
+		Name:      "policy_bytes",
+		Help:      "Estimated bytes of the rules held by cached tenant enforcers.",
+	})
+	tenantPolicyLoadDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "tenant_cache",
+		Name:      "policy_load_duration_seconds",
+		Help:      "Time taken to load the policy of one tenant from the store.",
+		Buckets:   prometheus.DefBuckets,
+	})
+)
+
+// labelLimiter bounds the values a label takes. Values matching a configured
+// prefix are reported as that prefix; without prefixes the first limit
+// distinct values are reported as is. Everything else becomes otherLabel.
+type labelLimiter struct {
+	prefixes []string
+	limit    int
+
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+func newLabelLimiter(prefixes []string, limit int) *labelLimiter {
+	return &labelLimiter{
+		prefixes: prefixes,
+		limit:    limit,
+		seen:     make(map[string]struct{}),
+	}
+}
+
+func (l *labelLimiter) value(v string) string {
+	if len(l.prefixes) > 0 {
+		for _, prefix := range l.prefixes {
+			if strings.HasPrefix(v, prefix) {
+				return prefix
+			}
+		}
+		return otherLabel
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.seen[v]; ok {
+		return v
+	}
+	if len(l.seen) >= l.limit {
+		return otherLabel
+	}
+	l.seen[v] = struct{}{}
+	return v
+}
+
+// recordDecision counts the outcome of an authorization rpc
+func (server *Server) recordDecision(rpc, resource, action string, allowed bool, err error) {
+	verdict := "deny"
+	switch {
+	case err != nil:
+		verdict = "error"
+	case allowed:
+		verdict = "allow"
+	}
+	decisions.WithLabelValues(rpc, verdict, server.resourceLabels.value(resource), server.actionLabels.value(action)).Inc()
+}
+
+func observeSince(o prometheus.Observer, start time.Time) {
+	o.Observe(time.Since(start).Seconds())
+}
+
+// tokenFailureReason classifies why a token could not be resolved
+func tokenFailureReason(err error) string {
+	switch {
+	case errors.Is(err, errUnknownKey):
+		return "unknown_key"
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "expired"
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return "not_valid_yet"
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return "malformed"
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return "signature_invalid"
+	}
+	if s, ok := status.FromError(err); ok {
+		return strings.ToLower(s.Code().String())
+	}
+	return "unknown"
+}
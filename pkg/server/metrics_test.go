@@ -0,0 +1,43 @@
+package server
+
+import "testing"
+
+func TestLabelLimiter(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefixes []string
+		limit    int
+		values   []string
+		want     []string
+	}{
+		{
+			name:   "first values kept",
+			limit:  2,
+			values: []string{"a", "b", "c", "a", "b", "d"},
+			want:   []string{"a", "b", otherLabel, "a", "b", otherLabel},
+		},
+		{
+			name:     "prefixes",
+			prefixes: []string{"room/", "room/live/", "data"},
+			limit:    1,
+			values:   []string{"room/1", "room/2", "data", "database", "other"},
+			want:     []string{"room/", "room/", "data", "data", otherLabel},
+		},
+		{
+			name:   "no values",
+			limit:  0,
+			values: []string{"a"},
+			want:   []string{otherLabel},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLabelLimiter(tt.prefixes, tt.limit)
+			for i, v := range tt.values {
+				if got := l.value(v); got != tt.want[i] {
+					t.Errorf("value(%q) = %q, want %q", v, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -25,3 +25,11 @@ func WithAuth(auth auth.Client) Option {
 		}
 	}
 }
+
+// WithTracing enables spans around RPCs, token validation, policy load and
+// enforcement, exported by the global tracer provider
+func WithTracing(enabled bool) Option {
+	return func(s *optionsStruct) {
+		s.tracingEnabled = enabled
+	}
+}
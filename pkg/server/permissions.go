@@ -0,0 +1,185 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/100mslive/packages/log"
+	accesspb "github.com/piyush1104/access/pkg/internal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// ListPermissions lists the resources and actions a subject, given directly or
+// through the subjects of a token, is allowed after expanding its roles
+func (server *Server) ListPermissions(ctx context.Context, req *accesspb.ListPermissionsRequest) (*accesspb.ListPermissionsReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+
+	subjects := []string{req.GetSubject()}
+	if token := req.GetToken(); token != "" {
+		if req.GetSubject() != "" {
+			return nil, status.Error(codes.InvalidArgument, "only one of subject and token may be set")
+		}
+		var err error
+		if subjects, err = server.tokenSubjects(ctx, req.GetTokenType(), token); err != nil {
+			return nil, err
+		}
+	} else if req.GetSubject() == "" {
+		return nil, status.Error(codes.InvalidArgument, "subject or token field is required")
+	}
+
+	offset, size, err := page(req.GetPageToken(), req.GetPageSize())
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := server.getEnforcer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var policies [][]string
+	for _, subject := range subjects {
+		implicit, err := e.GetImplicitPermissionsForUser(subject)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, implicit...)
+	}
+
+	// the same permission can be reached through several subjects and roles
+	seen := make(map[[2]string]bool, len(policies))
+	var permissions []*accesspb.Permission
+	for _, policy := range policies {
+		if len(policy) < 3 {
+			continue
+		}
+		key := [2]string{policy[1], policy[2]}
+		if seen[key] || !strings.HasPrefix(key[0], req.GetResourcePrefix()) {
+			continue
+		}
+		seen[key] = true
+		permissions = append(permissions, &accesspb.Permission{
+			Resource: key[0],
+			Action:   key[1],
+		})
+	}
+	sort.Slice(permissions, func(i, j int) bool {
+		if permissions[i].Resource != permissions[j].Resource {
+			return permissions[i].Resource < permissions[j].Resource
+		}
+		return permissions[i].Action < permissions[j].Action
+	})
+
+	reply := &accesspb.ListPermissionsReply{}
+	if offset >= len(permissions) {
+		return reply, nil
+	}
+	end := offset + size
+	if end < len(permissions) {
+		reply.NextPageToken = strconv.Itoa(end)
+	} else {
+		end = len(permissions)
+	}
+	reply.Permissions = permissions[offset:end]
+	return reply, nil
+}
+
+// page decodes a page token, the offset of the first item, and clamps the
+// requested page size
+func page(token string, size int32) (int, int, error) {
+	offset := 0
+	if token != "" {
+		var err error
+		if offset, err = strconv.Atoi(token); err != nil || offset < 0 {
+			return 0, 0, status.Error(codes.InvalidArgument, "invalid page token")
+		}
+	}
+	switch {
+	case size <= 0:
+		size = defaultPageSize
+	case size > maxPageSize:
+		size = maxPageSize
+	}
+	return offset, int(size), nil
+}
+
+// ListSubjects lists the subjects allowed action on resource, directly or
+// through roles, each with the shortest chain of roles granting it
+func (server *Server) ListSubjects(ctx context.Context, req *accesspb.ListSubjectsRequest) (*accesspb.ListSubjectsReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+
+	resource := req.GetResource()
+	if resource == "" {
+		return nil, status.Error(codes.InvalidArgument, "resource field is required")
+	}
+	action := req.GetAction()
+	if action == "" {
+		return nil, status.Error(codes.InvalidArgument, "action field is required")
+	}
+
+	e, err := server.getEnforcer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// members maps a role to the subjects assigned to it
+	members := make(map[string][]string)
+	for _, link := range e.GetGroupingPolicy() {
+		if len(link) < 2 {
+			continue
+		}
+		members[link[1]] = append(members[link[1]], link[0])
+	}
+
+	// walk from the policy subjects down to their members, breadth first so
+	// every subject keeps its shortest path
+	next := make(map[string]string)
+	var queue []string
+	for _, policy := range e.GetFilteredPolicy(1, resource, action) {
+		if _, ok := next[policy[0]]; ok {
+			continue
+		}
+		next[policy[0]] = ""
+		queue = append(queue, policy[0])
+	}
+	for len(queue) > 0 {
+		role := queue[0]
+		queue = queue[1:]
+		for _, member := range members[role] {
+			if _, ok := next[member]; ok {
+				continue
+			}
+			next[member] = role
+			queue = append(queue, member)
+		}
+	}
+
+	reply := &accesspb.ListSubjectsReply{}
+	for subject := range next {
+		grant := &accesspb.SubjectGrant{
+			Subject: subject,
+			Role:    len(members[subject]) > 0,
+		}
+		for s := subject; s != ""; s = next[s] {
+			grant.Path = append(grant.Path, s)
+		}
+		reply.Subjects = append(reply.Subjects, grant)
+	}
+	sort.Slice(reply.Subjects, func(i, j int) bool {
+		return reply.Subjects[i].Subject < reply.Subjects[j].Subject
+	})
+	return reply, nil
+}
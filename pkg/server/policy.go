@@ -0,0 +1,277 @@
+package server
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/100mslive/packages/log"
+	accesspb "github.com/piyush1104/access/pkg/internal"
+	"github.com/piyush1104/access/pkg/policy"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// ListPolicies lists the policies matching the request, empty fields matching
+// any value
+func (server *Server) ListPolicies(ctx context.Context, req *accesspb.ListPoliciesRequest) (*accesspb.ListPoliciesReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+
+	e, err := server.getEnforcer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules := e.GetFilteredPolicy(0, req.GetSubject(), req.GetResource(), req.GetAction())
+	policies := make([]*accesspb.Policy, 0, len(rules))
+	for _, rule := range rules {
+		if len(rule) < 3 {
+			continue
+		}
+		policies = append(policies, &accesspb.Policy{
+			Subject:  rule[0],
+			Resource: rule[1],
+			Action:   rule[2],
+		})
+	}
+	return &accesspb.ListPoliciesReply{Policies: policies}, nil
+}
+
+func validatePolicy(rule *accesspb.Policy) error {
+	if rule.GetSubject() == "" {
+		return status.Error(codes.InvalidArgument, "subject field is required")
+	}
+	if rule.GetResource() == "" {
+		return status.Error(codes.InvalidArgument, "resource field is required")
+	}
+	if rule.GetAction() == "" {
+		return status.Error(codes.InvalidArgument, "action field is required")
+	}
+	return nil
+}
+
+// AddPolicy allows a subject an action on a resource, Changed being false
+// when it already was
+func (server *Server) AddPolicy(ctx context.Context, req *accesspb.PolicyRequest) (*accesspb.ChangeReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+	rule := req.GetPolicy()
+	if err := validatePolicy(rule); err != nil {
+		return nil, err
+	}
+	if err := server.checkResource(ctx, rule.Resource, rule.Action); err != nil {
+		return nil, err
+	}
+
+	added := policy.Rule{PType: "p", Values: []string{rule.Subject, rule.Resource, rule.Action}}
+	changed := false
+	err := server.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// an added rule stays after just-in-time grants of it expire
+		if err := policy.KeepGrants(tx, []policy.Rule{added}); err != nil {
+			return err
+		}
+		exists, err := hasRule(tx, added.Values)
+		if err != nil || exists {
+			return err
+		}
+		a, err := txAdapter(tx)
+		if err != nil {
+			return err
+		}
+		changed = true
+		return a.AddPolicy("p", "p", added.Values)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		server.policyChanged(added)
+	}
+	return &accesspb.ChangeReply{Changed: changed}, nil
+}
+
+// RemovePolicy removes a policy, Changed being false when there was none
+func (server *Server) RemovePolicy(ctx context.Context, req *accesspb.PolicyRequest) (*accesspb.ChangeReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+	rule := req.GetPolicy()
+	if err := validatePolicy(rule); err != nil {
+		return nil, err
+	}
+
+	e, err := server.getEnforcer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := e.RemovePolicy(rule.Subject, rule.Resource, rule.Action)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		server.policyChanged(policy.Rule{PType: "p", Values: []string{rule.Subject, rule.Resource, rule.Action}})
+	}
+	return &accesspb.ChangeReply{Changed: changed}, nil
+}
+
+// ListRoles lists the role assignments matching the request, empty fields
+// matching any value
+func (server *Server) ListRoles(ctx context.Context, req *accesspb.ListRolesRequest) (*accesspb.ListRolesReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+
+	e, err := server.getEnforcer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules := e.GetFilteredGroupingPolicy(0, req.GetSubject(), req.GetRole())
+	roles := make([]*accesspb.RoleAssignment, 0, len(rules))
+	for _, rule := range rules {
+		if len(rule) < 2 {
+			continue
+		}
+		roles = append(roles, &accesspb.RoleAssignment{
+			Subject: rule[0],
+			Role:    rule[1],
+		})
+	}
+	return &accesspb.ListRolesReply{Roles: roles}, nil
+}
+
+func validateRole(role *accesspb.RoleAssignment) error {
+	if role.GetSubject() == "" {
+		return status.Error(codes.InvalidArgument, "subject field is required")
+	}
+	if role.GetRole() == "" {
+		return status.Error(codes.InvalidArgument, "role field is required")
+	}
+	return nil
+}
+
+// AssignRole makes a subject a member of a role, Changed being false when it
+// already was
+func (server *Server) AssignRole(ctx context.Context, req *accesspb.RoleRequest) (*accesspb.ChangeReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+	role := req.GetRole()
+	if err := validateRole(role); err != nil {
+		return nil, err
+	}
+
+	e, err := server.getEnforcer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := e.AddGroupingPolicy(role.Subject, role.Role)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		server.policyChanged(policy.Rule{PType: "g", Values: []string{role.Subject, role.Role}})
+	}
+	return &accesspb.ChangeReply{Changed: changed}, nil
+}
+
+// UnassignRole removes a subject from a role, Changed being false when it was
+// not a member
+func (server *Server) UnassignRole(ctx context.Context, req *accesspb.RoleRequest) (*accesspb.ChangeReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+	role := req.GetRole()
+	if err := validateRole(role); err != nil {
+		return nil, err
+	}
+
+	e, err := server.getEnforcer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := e.RemoveGroupingPolicy(role.Subject, role.Role)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		server.policyChanged(policy.Rule{PType: "g", Values: []string{role.Subject, role.Role}})
+	}
+	return &accesspb.ChangeReply{Changed: changed}, nil
+}
+
+// ExportPolicies returns the complete policy, p rules and g links, in the
+// requested format
+func (server *Server) ExportPolicies(ctx context.Context, req *accesspb.ExportPoliciesRequest) (*accesspb.ExportPoliciesReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+	format := req.GetFormat()
+	if format == "" {
+		format = policy.FormatCSV
+	}
+
+	set, err := policy.Load(server.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := policy.Write(format, &buf, set); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &accesspb.ExportPoliciesReply{Data: buf.Bytes()}, nil
+}
+
+// ImportPolicies merges the policy into the store or replaces the store with
+// it, returning the rules added and removed. A dry run only returns them.
+func (server *Server) ImportPolicies(ctx context.Context, req *accesspb.ImportPoliciesRequest) (*accesspb.ImportPoliciesReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+	format := req.GetFormat()
+	if format == "" {
+		format = policy.FormatCSV
+	}
+
+	desired, err := policy.Read(format, bytes.NewReader(req.GetData()))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := server.checkRules(ctx, desired.Rules()); err != nil {
+		return nil, err
+	}
+	replace := req.GetMode() == accesspb.ImportMode_IMPORT_MODE_REPLACE
+	diff, err := policy.Import(server.db.WithContext(ctx), desired, replace, req.GetDryRun())
+	if err != nil {
+		return nil, err
+	}
+	applied := !req.GetDryRun() && !diff.Empty()
+	if applied {
+		server.policyChanged(append(diff.Add, diff.Remove...)...)
+	}
+	return &accesspb.ImportPoliciesReply{
+		Added:   policyRules(diff.Add),
+		Removed: policyRules(diff.Remove),
+		Applied: applied,
+	}, nil
+}
+
+func policyRules(rules []policy.Rule) []*accesspb.PolicyRule {
+	out := make([]*accesspb.PolicyRule, 0, len(rules))
+	for _, rule := range rules {
+		out = append(out, &accesspb.PolicyRule{
+			PType:  rule.PType,
+			Values: rule.Values,
+		})
+	}
+	return out
+}
@@ -0,0 +1,390 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/100mslive/packages/log"
+	accesspb "github.com/piyush1104/access/pkg/internal"
+	"github.com/piyush1104/access/pkg/policy"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// registryRefresh is how long the resource types stored by other servers
+// take to be seen
+const registryRefresh = 10 * time.Second
+
+// ResourceTypeConfig is a kind of resource, the resources starting with
+// Prefix, and the actions allowed on it
+type ResourceTypeConfig struct {
+	Name    string   `mapstructure:"name,omitempty"`
+	Prefix  string   `mapstructure:"prefix,omitempty"`
+	Actions []string `mapstructure:"actions,omitempty"`
+}
+
+// storedResourceType is a resource type added through PutResourceType
+type storedResourceType struct {
+	Name    string `gorm:"primaryKey;size:100"`
+	Prefix  string `gorm:"size:255;uniqueIndex"`
+	Actions string `gorm:"size:1024"`
+}
+
+func (storedResourceType) TableName() string {
+	return "resource_types"
+}
+
+type resourceType struct {
+	ResourceTypeConfig
+	static bool
+}
+
+// allows tells whether action is one of the type, or approveAction, which
+// every type has implicitly
+func (t *resourceType) allows(action string) bool {
+	if action == approveAction {
+		return true
+	}
+	for _, a := range t.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *resourceType) proto() *accesspb.ResourceType {
+	return &accesspb.ResourceType{
+		Name:    t.Name,
+		Prefix:  t.Prefix,
+		Actions: t.Actions,
+		Static:  t.static,
+	}
+}
+
+// registry holds the resource types of the config and of the store. Without
+// any, resources and actions are not checked.
+type registry struct {
+	mu     sync.RWMutex
+	static []*resourceType
+	stored []*resourceType
+	// sorted are every type as types lists them, built on changes
+	sorted []*resourceType
+	loaded time.Time
+	// refreshing lets one caller at a time read the store
+	refreshing sync.Mutex
+}
+
+// staticTypes checks the resource types of the config
+func staticTypes(types []ResourceTypeConfig) ([]*resourceType, error) {
+	static := make([]*resourceType, 0, len(types))
+	names, prefixes := make(map[string]bool), make(map[string]bool)
+	for _, t := range types {
+		if err := validateResourceType(&accesspb.ResourceType{Name: t.Name, Prefix: t.Prefix, Actions: t.Actions}); err != nil {
+			return nil, fmt.Errorf("resource type %q: %s", t.Name, status.Convert(err).Message())
+		}
+		if names[t.Name] || prefixes[t.Prefix] {
+			return nil, fmt.Errorf("resource type %q: name or prefix %q is used twice", t.Name, t.Prefix)
+		}
+		names[t.Name], prefixes[t.Prefix] = true, true
+		static = append(static, &resourceType{ResourceTypeConfig: t, static: true})
+	}
+	return static, nil
+}
+
+func (r *registry) setStatic(static []*resourceType) {
+	r.mu.Lock()
+	r.static = static
+	r.sort()
+	r.mu.Unlock()
+}
+
+func (r *registry) fresh() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return time.Since(r.loaded) < registryRefresh
+}
+
+// refresh reads the stored types again once they are older than
+// registryRefresh, or always when forced. Concurrent callers wait for one
+// read. Unless forced, a failed read keeps the types read last, until the
+// next refresh.
+func (r *registry) refresh(db *gorm.DB, force bool) error {
+	if !force && r.fresh() {
+		return nil
+	}
+	r.refreshing.Lock()
+	defer r.refreshing.Unlock()
+	if !force && r.fresh() {
+		return nil
+	}
+
+	var rows []storedResourceType
+	if err := db.Find(&rows).Error; err != nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if force || r.loaded.IsZero() {
+			return err
+		}
+		logger.Println("Error!!!Failed to refresh resource types, keeping the last ones:", err)
+		r.loaded = time.Now()
+		return nil
+	}
+	stored := make([]*resourceType, 0, len(rows))
+	for _, row := range rows {
+		stored = append(stored, row.resourceType())
+	}
+	r.mu.Lock()
+	r.stored, r.loaded = stored, time.Now()
+	r.sort()
+	r.mu.Unlock()
+	return nil
+}
+
+// adminResources is the resource type of admin grants, which are policies
+// too, unless a registered type takes over its prefix
+var adminResources = &resourceType{
+	ResourceTypeConfig: ResourceTypeConfig{Name: "access-rpc", Prefix: adminNamespace, Actions: []string{adminAction}},
+	static:             true,
+}
+
+// types lists every type, config ones first, each group by name. The list
+// is shared and must not be changed.
+func (r *registry) types() []*resourceType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.sorted
+}
+
+// sort builds the list of types, under the registry lock
+func (r *registry) sort() {
+	types := append(append([]*resourceType(nil), r.static...), r.stored...)
+	if len(types) == 0 {
+		r.sorted = nil
+		return
+	}
+	admin := true
+	for _, t := range types {
+		if t.Prefix == adminNamespace {
+			admin = false
+		}
+	}
+	if admin {
+		types = append(types, adminResources)
+	}
+	sort.SliceStable(types, func(i, j int) bool {
+		if types[i].static != types[j].static {
+			return types[i].static
+		}
+		return types[i].Name < types[j].Name
+	})
+	r.sorted = types
+}
+
+// lookup finds the type of resource by longest prefix, reporting whether the
+// registry checks resources at all
+func (r *registry) lookup(resource string) (*resourceType, bool) {
+	types := r.types()
+	var found *resourceType
+	for _, t := range types {
+		if strings.HasPrefix(resource, t.Prefix) && (found == nil || len(t.Prefix) > len(found.Prefix)) {
+			found = t
+		}
+	}
+	return found, len(types) > 0
+}
+
+func (row storedResourceType) resourceType() *resourceType {
+	var actions []string
+	if row.Actions != "" {
+		actions = strings.Split(row.Actions, ",")
+	}
+	return &resourceType{ResourceTypeConfig: ResourceTypeConfig{
+		Name:    row.Name,
+		Prefix:  row.Prefix,
+		Actions: actions,
+	}}
+}
+
+// checkResource rejects a resource of no registered type, or an action its
+// type does not allow
+func (server *Server) checkResource(ctx context.Context, resource, action string) error {
+	if err := server.registry.refresh(server.db.WithContext(ctx), false); err != nil {
+		return err
+	}
+	return server.registry.check(resource, action)
+}
+
+func (r *registry) check(resource, action string) error {
+	t, checked := r.lookup(resource)
+	if !checked {
+		return nil
+	}
+	if t == nil {
+		return status.Errorf(codes.InvalidArgument, "resource %q is of no registered resource type", resource)
+	}
+	if !t.allows(action) {
+		return status.Errorf(codes.InvalidArgument, "action %q is not allowed on %s resources", action, t.Name)
+	}
+	return nil
+}
+
+// checkRules checks the resource and action of every p rule
+func (server *Server) checkRules(ctx context.Context, rules []policy.Rule) error {
+	for _, rule := range rules {
+		if rule.PType != "p" || len(rule.Values) < 3 {
+			continue
+		}
+		if err := server.checkResource(ctx, rule.Values[1], rule.Values[2]); err != nil {
+			return status.Errorf(codes.InvalidArgument, "%s: %s", rule, status.Convert(err).Message())
+		}
+	}
+	return nil
+}
+
+// CheckRules checks the resource and action of every p rule against the
+// resource types of config and of db, as the admin RPCs do, for the offline
+// commands writing the store
+func CheckRules(db *gorm.DB, config *Config, rules []policy.Rule) error {
+	static, err := staticTypes(config.Resources)
+	if err != nil {
+		return err
+	}
+	r := &registry{}
+	r.setStatic(static)
+	if db.Migrator().HasTable(&storedResourceType{}) {
+		if err := r.refresh(db, true); err != nil {
+			return err
+		}
+	}
+	for _, rule := range rules {
+		if rule.PType != "p" || len(rule.Values) < 3 {
+			continue
+		}
+		if err := r.check(rule.Values[1], rule.Values[2]); err != nil {
+			return fmt.Errorf("%s: %s", rule, status.Convert(err).Message())
+		}
+	}
+	return nil
+}
+
+// ListResourceTypes lists the registered resource types, those of the config
+// first
+func (server *Server) ListResourceTypes(ctx context.Context, req *accesspb.ListResourceTypesRequest) (*accesspb.ListResourceTypesReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+
+	if err := server.registry.refresh(server.db.WithContext(ctx), true); err != nil {
+		return nil, err
+	}
+	types := server.registry.types()
+	reply := &accesspb.ListResourceTypesReply{Types: make([]*accesspb.ResourceType, 0, len(types))}
+	for _, t := range types {
+		reply.Types = append(reply.Types, t.proto())
+	}
+	return reply, nil
+}
+
+func validateResourceType(t *accesspb.ResourceType) error {
+	if t.GetName() == "" {
+		return status.Error(codes.InvalidArgument, "name field is required")
+	}
+	if t.GetPrefix() == "" {
+		return status.Error(codes.InvalidArgument, "prefix field is required")
+	}
+	if len(t.GetActions()) == 0 {
+		return status.Error(codes.InvalidArgument, "actions field is required")
+	}
+	for _, action := range t.GetActions() {
+		if action == "" || strings.Contains(action, ",") {
+			return status.Errorf(codes.InvalidArgument, "action %q must be non-empty without commas", action)
+		}
+	}
+	return nil
+}
+
+// PutResourceType adds a resource type or replaces the one of the same name,
+// Changed being false when it was already the same
+func (server *Server) PutResourceType(ctx context.Context, req *accesspb.ResourceTypeRequest) (*accesspb.ChangeReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+	t := req.GetType()
+	if err := validateResourceType(t); err != nil {
+		return nil, err
+	}
+	for _, static := range server.registry.types() {
+		if !static.static {
+			continue
+		}
+		if static.Name == t.Name {
+			return nil, ErrResourceTypeStatic
+		}
+		if static.Prefix == t.Prefix {
+			return nil, status.Errorf(codes.AlreadyExists, "prefix %q is used by resource type %s", t.Prefix, static.Name)
+		}
+	}
+
+	row := storedResourceType{Name: t.Name, Prefix: t.Prefix, Actions: strings.Join(t.Actions, ",")}
+	changed := false
+	err := server.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current []storedResourceType
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("name = ? OR prefix = ?", row.Name, row.Prefix).Find(&current).Error
+		if err != nil {
+			return err
+		}
+		for _, c := range current {
+			if c.Name != row.Name {
+				return status.Errorf(codes.AlreadyExists, "prefix %q is used by resource type %s", row.Prefix, c.Name)
+			}
+			if c == row {
+				return nil
+			}
+		}
+		changed = true
+		return tx.Save(&row).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := server.registry.refresh(server.db.WithContext(ctx), true); err != nil {
+		return nil, err
+	}
+	return &accesspb.ChangeReply{Changed: changed}, nil
+}
+
+// DeleteResourceType removes a resource type added through PutResourceType,
+// Changed being false when there was none
+func (server *Server) DeleteResourceType(ctx context.Context, req *accesspb.DeleteResourceTypeRequest) (*accesspb.ChangeReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name field is required")
+	}
+	for _, t := range server.registry.types() {
+		if t.static && t.Name == req.GetName() {
+			return nil, ErrResourceTypeStatic
+		}
+	}
+
+	result := server.db.WithContext(ctx).Delete(&storedResourceType{Name: req.GetName()})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if err := server.registry.refresh(server.db.WithContext(ctx), true); err != nil {
+		return nil, err
+	}
+	return &accesspb.ChangeReply{Changed: result.RowsAffected > 0}, nil
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/piyush1104/access/pkg/policy"
+)
+
+func TestCheckRules(t *testing.T) {
+	db := testDB(t)
+	if err := db.AutoMigrate(&storedResourceType{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&storedResourceType{Name: "recording", Prefix: "recording/", Actions: "read,delete"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{Resources: []ResourceTypeConfig{{Name: "room", Prefix: "room/", Actions: []string{"join"}}}}
+
+	tests := []struct {
+		name    string
+		rule    policy.Rule
+		wantErr bool
+	}{
+		{name: "config type", rule: policy.Rule{PType: "p", Values: []string{"alice", "room/1", "join"}}},
+		{name: "stored type", rule: policy.Rule{PType: "p", Values: []string{"alice", "recording/1", "delete"}}},
+		{name: "implicit approve", rule: policy.Rule{PType: "p", Values: []string{"alice", "recording/1", approveAction}}},
+		{name: "admin grant", rule: policy.Rule{PType: "p", Values: []string{"alice", adminNamespace + "AddPolicy", adminAction}}},
+		{name: "role link", rule: policy.Rule{PType: "g", Values: []string{"alice", "admin"}}},
+		{name: "unknown action", rule: policy.Rule{PType: "p", Values: []string{"alice", "room/1", "publish"}}, wantErr: true},
+		{name: "unknown resource", rule: policy.Rule{PType: "p", Values: []string{"alice", "data", "read"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckRules(db, config, []policy.Rule{tt.rule})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckRules(%s) = %v, want error %v", tt.rule, err, tt.wantErr)
+			}
+		})
+	}
+}
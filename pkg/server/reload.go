@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/piyush1104/access/pkg/policy"
+	"google.golang.org/grpc"
+)
+
+// tlsStore hands each handshake the current TLS config, so certificates
+// rotate for new connections while established ones are kept
+type tlsStore struct {
+	mu     sync.RWMutex
+	config *tls.Config
+}
+
+func (s *tlsStore) set(config *tls.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = config
+}
+
+func (s *tlsStore) get(*tls.ClientHelloInfo) (*tls.Config, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config, nil
+}
+
+// newModel parses the current model, a fresh one per enforcer since loading
+// policy fills it
+func (server *Server) newModel() (model.Model, error) {
+	return model.NewModelFromString(server.modelText())
+}
+
+func (server *Server) modelText() string {
+	server.modelMu.RLock()
+	defer server.modelMu.RUnlock()
+	return server.model
+}
+
+// reloadable are the Config fields Reload applies, every other change
+// requiring a restart
+var reloadable = map[string]bool{
+	"Cert":      true,
+	"Key":       true,
+	"ClientCA":  true,
+	"Logging":   true,
+	"Caching":   true,
+	"Model":     true,
+	"Resources": true,
+}
+
+// offline are the Config fields only the offline commands read, which
+// Reload neither applies nor rejects
+var offline = map[string]bool{
+	"Lint": true,
+}
+
+// Reload applies the safe changes of config to the running server: the TLS
+// certificates and client CA bundle are read again from disk, logging and
+// caching are toggled and the model is read again once it validates. Configs
+// changing anything else are rejected as a whole. Reloads wait for Start to
+// set the server up.
+func (server *Server) Reload(config *Config) error {
+	config.SetDefaults()
+	select {
+	case <-server.started:
+	case <-server.shutdown:
+		return ErrServerNotConnected
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	current := server.config
+
+	var unsafe, changed []string
+	old, next := reflect.ValueOf(current).Elem(), reflect.ValueOf(config).Elem()
+	for i := 0; i < old.NumField(); i++ {
+		name := old.Type().Field(i).Name
+		if offline[name] || reflect.DeepEqual(old.Field(i).Interface(), next.Field(i).Interface()) {
+			continue
+		}
+		if reloadable[name] {
+			changed = append(changed, name)
+		} else {
+			unsafe = append(unsafe, name)
+		}
+	}
+	if len(unsafe) > 0 {
+		return fmt.Errorf("changing %s needs a restart", strings.Join(unsafe, ", "))
+	}
+	if (current.Cert == "") != (config.Cert == "") || (current.Key == "") != (config.Key == "") {
+		return fmt.Errorf("enabling or disabling TLS needs a restart")
+	}
+
+	// validate everything before applying anything
+	var tlsConfig *tls.Config
+	if server.tls != nil {
+		var err error
+		if tlsConfig, err = serverTLSConfig(config); err != nil {
+			return err
+		}
+	}
+	text, err := policy.LoadModel(config.Model)
+	if err != nil {
+		return err
+	}
+	static, err := staticTypes(config.Resources)
+	if err != nil {
+		return err
+	}
+
+	if tlsConfig != nil {
+		server.tls.set(tlsConfig)
+	}
+	server.modelMu.Lock()
+	modelChanged := text != server.model
+	server.model = text
+	server.modelMu.Unlock()
+	if modelChanged {
+		changed = append(changed, "model rules")
+		// tenant enforcers were created with the old model
+		server.tenants.purge()
+	}
+	server.registry.setStatic(static)
+	server.setLogging(config.Logging)
+	if server.tokenCache != nil {
+		server.tokenCache.setEnabled(config.Caching)
+	}
+
+	current.Cert, current.Key, current.ClientCA = config.Cert, config.Key, config.ClientCA
+	current.Logging, current.Caching, current.Model = config.Logging, config.Caching, config.Model
+	current.Resources = config.Resources
+	if len(changed) > 0 {
+		logger.Println("Reloaded config, changed:", strings.Join(changed, ", "))
+	} else {
+		logger.Println("Reloaded config, certificates read again")
+	}
+	return nil
+}
+
+func (server *Server) setConnected(connected bool) {
+	var v int32
+	if connected {
+		v = 1
+	}
+	atomic.StoreInt32(&server.connected, v)
+}
+
+func (server *Server) isConnected() bool {
+	return atomic.LoadInt32(&server.connected) == 1
+}
+
+func (server *Server) setLogging(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&server.logging, v)
+}
+
+func (server *Server) loggingEnabled() bool {
+	return atomic.LoadInt32(&server.logging) == 1
+}
+
+// unaryIfLogging runs i only while logging is enabled
+func (server *Server) unaryIfLogging(i grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !server.loggingEnabled() {
+			return handler(ctx, req)
+		}
+		return i(ctx, req, info, handler)
+	}
+}
+
+// streamIfLogging runs i only while logging is enabled
+func (server *Server) streamIfLogging(i grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !server.loggingEnabled() {
+			return handler(srv, ss)
+		}
+		return i(srv, ss, info, handler)
+	}
+}
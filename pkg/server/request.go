@@ -0,0 +1,370 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/100mslive/packages/log"
+	"github.com/casbin/casbin/v2"
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	accesspb "github.com/piyush1104/access/pkg/internal"
+	"github.com/piyush1104/access/pkg/policy"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// approveAction is the action an approver needs on a resource, granted through
+// regular policies, to decide access requests for that resource
+const approveAction = policy.ApproveAction
+
+// accessRequest is a just-in-time request for a time-limited grant. Granted
+// records whether approving it added the policy, so only that request removes
+// it again when its window closes.
+type accessRequest struct {
+	ID        uint64 `gorm:"primaryKey;autoIncrement"`
+	Subject   string `gorm:"size:100;index"`
+	Resource  string `gorm:"size:100"`
+	Action    string `gorm:"size:100"`
+	Reason    string `gorm:"size:255"`
+	Duration  int64
+	Status    accesspb.AccessRequestStatus `gorm:"index"`
+	Approver  string                       `gorm:"size:100"`
+	Comment   string                       `gorm:"size:255"`
+	Granted   bool
+	CreatedAt int64 `gorm:"autoCreateTime"`
+	DecidedAt int64
+	ExpiresAt int64 `gorm:"index"`
+}
+
+func (accessRequest) TableName() string {
+	return "access_requests"
+}
+
+// rule is the p rule the request grants
+func (r *accessRequest) rule() []string {
+	return []string{r.Subject, r.Resource, r.Action}
+}
+
+// hasRule tells whether casbin_rule holds the p rule, locking the rows read
+// until tx ends
+func hasRule(tx *gorm.DB, rule []string) (bool, error) {
+	var n int64
+	err := tx.Model(&gormadapter.CasbinRule{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("ptype = ? AND v0 = ? AND v1 = ? AND v2 = ? AND v3 = ''", "p", rule[0], rule[1], rule[2]).
+		Count(&n).Error
+	return n > 0, err
+}
+
+func (r *accessRequest) proto() *accesspb.AccessRequest {
+	return &accesspb.AccessRequest{
+		ID:        r.ID,
+		Subject:   r.Subject,
+		Resource:  r.Resource,
+		Action:    r.Action,
+		Reason:    r.Reason,
+		Duration:  r.Duration,
+		Status:    r.Status,
+		Approver:  r.Approver,
+		Comment:   r.Comment,
+		CreatedAt: r.CreatedAt,
+		DecidedAt: r.DecidedAt,
+		ExpiresAt: r.ExpiresAt,
+	}
+}
+
+// RequestAccess files a pending request for a time-limited grant
+func (server *Server) RequestAccess(ctx context.Context, req *accesspb.RequestAccessRequest) (*accesspb.AccessRequestReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+
+	if req.GetSubject() == "" {
+		return nil, status.Error(codes.InvalidArgument, "subject field is required")
+	}
+	if req.GetResource() == "" {
+		return nil, status.Error(codes.InvalidArgument, "resource field is required")
+	}
+	if req.GetAction() == "" {
+		return nil, status.Error(codes.InvalidArgument, "action field is required")
+	}
+	if err := server.checkResource(ctx, req.GetResource(), req.GetAction()); err != nil {
+		return nil, err
+	}
+	if err := server.checkRequester(ctx, req.GetSubject()); err != nil {
+		return nil, err
+	}
+
+	duration := req.GetDuration()
+	if duration == 0 {
+		duration = int64(server.config.GrantDuration)
+	}
+	if duration < 0 || duration > int64(server.config.MaxGrantDuration) {
+		return nil, status.Errorf(codes.InvalidArgument, "duration must be between 1 and %d seconds", server.config.MaxGrantDuration)
+	}
+
+	r := &accessRequest{
+		Subject:  req.GetSubject(),
+		Resource: req.GetResource(),
+		Action:   req.GetAction(),
+		Reason:   req.GetReason(),
+		Duration: duration,
+		Status:   accesspb.AccessRequestStatus_ACCESS_REQUEST_STATUS_PENDING,
+	}
+	if err := server.db.WithContext(ctx).Create(r).Error; err != nil {
+		return nil, err
+	}
+
+	logger.Printf("Access request %d: %s wants %s on %s for %ds", r.ID, r.Subject, r.Action, r.Resource, r.Duration)
+	return &accesspb.AccessRequestReply{Request: r.proto()}, nil
+}
+
+// checkRequester lets callers request access for the subjects they
+// authenticated as, and for any other only with a grant on requestForOthers
+func (server *Server) checkRequester(ctx context.Context, subject string) error {
+	principals, err := server.principals(ctx)
+	if err != nil {
+		return err
+	}
+	for _, principal := range principals {
+		if principal == subject {
+			return nil
+		}
+	}
+	allowed, err := server.adminAllowed(ctx, principals, requestForOthers)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		logger.Printf("Error!!!%v may not request access for %s", principals, subject)
+		return ErrRequesterNotAllowed
+	}
+	return nil
+}
+
+// ApproveAccess approves a pending request and grants its policy until the
+// request expires
+func (server *Server) ApproveAccess(ctx context.Context, req *accesspb.ApproveAccessRequest) (*accesspb.AccessRequestReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+
+	e, err := server.getEnforcer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := server.decideAccess(ctx, e, req.GetID(), req.GetApprover(), req.GetComment(), func(tx *gorm.DB, r *accessRequest) error {
+		r.Status = accesspb.AccessRequestStatus_ACCESS_REQUEST_STATUS_APPROVED
+		r.ExpiresAt = r.DecidedAt + r.Duration
+		// an existing rule, permanent or held by another request, is left alone
+		exists, err := hasRule(tx, r.rule())
+		if err != nil || exists {
+			return err
+		}
+		a, err := txAdapter(tx)
+		if err != nil {
+			return err
+		}
+		r.Granted = true
+		return a.AddPolicy("p", "p", r.rule())
+	})
+	if err != nil {
+		return nil, err
+	}
+	if r.Granted {
+		server.policyChanged(policy.Rule{PType: "p", Values: r.rule()})
+	}
+
+	logger.Printf("Access request %d approved by %s until %d", r.ID, r.Approver, r.ExpiresAt)
+	return &accesspb.AccessRequestReply{Request: r.proto()}, nil
+}
+
+// RejectAccess rejects a pending request
+func (server *Server) RejectAccess(ctx context.Context, req *accesspb.RejectAccessRequest) (*accesspb.AccessRequestReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+
+	e, err := server.getEnforcer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := server.decideAccess(ctx, e, req.GetID(), req.GetApprover(), req.GetComment(), func(tx *gorm.DB, r *accessRequest) error {
+		r.Status = accesspb.AccessRequestStatus_ACCESS_REQUEST_STATUS_REJECTED
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Printf("Access request %d rejected by %s", r.ID, r.Approver)
+	return &accesspb.AccessRequestReply{Request: r.proto()}, nil
+}
+
+// ListAccessRequests lists requests, newest first, optionally filtered by
+// subject and status
+func (server *Server) ListAccessRequests(ctx context.Context, req *accesspb.ListAccessRequestsRequest) (*accesspb.ListAccessRequestsReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+
+	query := server.db.WithContext(ctx).Order("id desc")
+	if req.GetSubject() != "" {
+		query = query.Where("subject = ?", req.GetSubject())
+	}
+	if req.GetStatus() != accesspb.AccessRequestStatus_ACCESS_REQUEST_STATUS_UNSPECIFIED {
+		query = query.Where("status = ?", req.GetStatus())
+	}
+
+	var requests []accessRequest
+	if err := query.Find(&requests).Error; err != nil {
+		return nil, err
+	}
+
+	reply := &accesspb.ListAccessRequestsReply{}
+	for i := range requests {
+		reply.Requests = append(reply.Requests, requests[i].proto())
+	}
+	return reply, nil
+}
+
+// decideAccess locks a pending request, checks the approver against the
+// approve policy for its resource and saves the decision made by decide,
+// within the same transaction. The
+// approver is one of the principals the caller authenticated as, the one
+// named when not empty, and never the subject of the request.
+func (server *Server) decideAccess(ctx context.Context, e *casbin.Enforcer, id uint64, approver, comment string, decide func(tx *gorm.DB, r *accessRequest) error) (*accessRequest, error) {
+	if id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "id field is required")
+	}
+	principals, err := server.principals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	candidates := principals
+	if approver != "" {
+		candidates = nil
+		for _, principal := range principals {
+			if principal == approver {
+				candidates = []string{approver}
+			}
+		}
+		if candidates == nil {
+			logger.Printf("Error!!!%v may not decide as %s", principals, approver)
+			return nil, ErrApproverNotAllowed
+		}
+	}
+
+	r := &accessRequest{}
+	err = server.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(r, id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAccessRequestNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if r.Status != accesspb.AccessRequestStatus_ACCESS_REQUEST_STATUS_PENDING {
+			return ErrAccessRequestDecided
+		}
+		for _, candidate := range candidates {
+			if candidate == r.Subject {
+				continue
+			}
+			allowed, err := e.Enforce(candidate, r.Resource, approveAction)
+			if err != nil {
+				return err
+			}
+			if allowed {
+				r.Approver = candidate
+				break
+			}
+		}
+		if r.Approver == "" {
+			return ErrApproverNotAllowed
+		}
+		r.Comment = comment
+		r.DecidedAt = time.Now().Unix()
+		if err := decide(tx, r); err != nil {
+			return err
+		}
+		return tx.Save(r).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// expireGrants marks approved requests whose window has closed as expired and
+// revokes their policies. A grant still needed by another approved request for
+// the same rule is handed over to it instead of being removed.
+func (server *Server) expireGrants(ctx context.Context) error {
+	now := time.Now().Unix()
+	var expired []accessRequest
+	err := server.db.WithContext(ctx).
+		Where("status = ? AND expires_at <= ?", accesspb.AccessRequestStatus_ACCESS_REQUEST_STATUS_APPROVED, now).
+		Find(&expired).Error
+	if err != nil || len(expired) == 0 {
+		return err
+	}
+
+	for _, r := range expired {
+		revoked := false
+		err := server.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// another server may have expired it, or a permanent rule taken
+			// the grant over, since it was read
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&r, r.ID).Error
+			if err != nil {
+				return err
+			}
+			if r.Status != accesspb.AccessRequestStatus_ACCESS_REQUEST_STATUS_APPROVED || r.ExpiresAt > now {
+				return nil
+			}
+			granted := r.Granted
+			if err := tx.Model(&r).Updates(map[string]interface{}{
+				"status":  accesspb.AccessRequestStatus_ACCESS_REQUEST_STATUS_EXPIRED,
+				"granted": false,
+			}).Error; err != nil {
+				return err
+			}
+			if !granted {
+				return nil
+			}
+
+			next := &accessRequest{}
+			err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("status = ? AND expires_at > ? AND subject = ? AND resource = ? AND action = ?",
+					accesspb.AccessRequestStatus_ACCESS_REQUEST_STATUS_APPROVED, now, r.Subject, r.Resource, r.Action).
+				Order("expires_at desc").First(next).Error
+			if err == nil {
+				return tx.Model(next).Update("granted", true).Error
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			a, err := txAdapter(tx)
+			if err != nil {
+				return err
+			}
+			revoked = true
+			return a.RemovePolicy("p", "p", r.rule())
+		})
+		if err != nil {
+			return err
+		}
+		if revoked {
+			server.policyChanged(policy.Rule{PType: "p", Values: r.rule()})
+		}
+		logger.Printf("Access request %d expired: %s on %s for %s", r.ID, r.Action, r.Resource, r.Subject)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"testing"
+	"time"
+
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	"github.com/glebarez/sqlite"
+	accesspb "github.com/piyush1104/access/pkg/internal"
+	"github.com/piyush1104/access/pkg/policy"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"gorm.io/gorm"
+)
+
+const testModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+`
+
+// testDB opens an empty in-memory store with the tables of the server
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection would open its own in-memory database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&gormadapter.CasbinRule{}, &accessRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestExpireGrantsKeepsSyncedRules(t *testing.T) {
+	rule := policy.Rule{PType: "p", Values: []string{"alice", "room/1", "join"}}
+	tests := []struct {
+		name   string
+		synced bool
+		want   bool
+	}{
+		{name: "grant only", synced: false, want: false},
+		{name: "synced after the grant", synced: true, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t)
+			if err := db.Create(&gormadapter.CasbinRule{Ptype: "p", V0: "alice", V1: "room/1", V2: "join"}).Error; err != nil {
+				t.Fatal(err)
+			}
+			err := db.Create(&accessRequest{
+				Subject:   "alice",
+				Resource:  "room/1",
+				Action:    "join",
+				Status:    accesspb.AccessRequestStatus_ACCESS_REQUEST_STATUS_APPROVED,
+				Granted:   true,
+				ExpiresAt: time.Now().Add(-time.Minute).Unix(),
+			}).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.synced {
+				if _, err := policy.Sync(db, policy.NewSet([]policy.Rule{rule}), false, false); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			server := &Server{db: db, adminPolicy: &adminPolicy{}}
+			if err := server.expireGrants(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			set, err := policy.Load(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := set.Has(rule); got != tt.want {
+				t.Errorf("rule kept = %v, want %v", got, tt.want)
+			}
+			var r accessRequest
+			if err := db.First(&r).Error; err != nil {
+				t.Fatal(err)
+			}
+			if r.Status != accesspb.AccessRequestStatus_ACCESS_REQUEST_STATUS_EXPIRED {
+				t.Errorf("status = %v, want expired", r.Status)
+			}
+		})
+	}
+}
+
+// certContext is the context of a call made with a client certificate for
+// identity
+func certContext(identity string) context.Context {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: identity}}
+	info := credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}}
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: info})
+}
+
+func TestCheckRequester(t *testing.T) {
+	db := testDB(t)
+	if err := db.Create(&gormadapter.CasbinRule{Ptype: "p", V0: "ops", V1: adminNamespace + requestForOthers, V2: adminAction}).Error; err != nil {
+		t.Fatal(err)
+	}
+	server := &Server{
+		config:      &Config{Admin: AdminConfig{Superuser: "root"}},
+		db:          db,
+		model:       testModel,
+		tracer:      newTracer(false),
+		adminPolicy: &adminPolicy{},
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		subject string
+		wantErr error
+	}{
+		{name: "own subject", ctx: certContext("alice"), subject: "alice"},
+		{name: "other subject", ctx: certContext("alice"), subject: "bob", wantErr: ErrRequesterNotAllowed},
+		{name: "other subject with a grant", ctx: certContext("ops"), subject: "bob"},
+		{name: "other subject as superuser", ctx: certContext("root"), subject: "bob"},
+		{name: "unauthenticated", ctx: context.Background(), subject: "bob", wantErr: ErrAdminUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := server.checkRequester(tt.ctx, tt.subject); !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkRequester(%s) = %v, want %v", tt.subject, err, tt.wantErr)
+			}
+		})
+	}
+}
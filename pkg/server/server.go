@@ -2,23 +2,29 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/100mslive/auth"
+	gormadapter "github.com/casbin/gorm-adapter/v3"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_logrus "github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
 	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
 	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	accesspb "github.com/piyush1104/access/pkg/internal"
+	"github.com/piyush1104/access/pkg/policy"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/trace"
 	grpc "google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
@@ -26,6 +32,8 @@ import (
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
 )
 
 var (
@@ -50,21 +58,98 @@ type Config struct {
 	Logging  bool   `mapstructure:"logging,omitempty"`
 	Recovery bool   `mapstructure:"recovery,omitempty"`
 	Caching  bool   `mapstructure:"caching,omitempty"`
+	// Model is the casbin model file
+	Model string `mapstructure:"model,omitempty"`
+	// ClientAuth is optional or require to verify client certificates
+	// against the ClientCA bundle
+	ClientAuth string `mapstructure:"client_auth,omitempty"`
+	ClientCA   string `mapstructure:"client_ca,omitempty"`
+	// Callers restrict the RPCs each caller may use. Without callers every
+	// RPC is open to every caller.
+	Callers []CallerConfig `mapstructure:"callers,omitempty"`
+	// Limits rate limit callers and shed load
+	Limits LimitConfig `mapstructure:"limits,omitempty"`
+	// Admin requires grants in the admin namespace for administrative RPCs
+	Admin AdminConfig `mapstructure:"admin,omitempty"`
+	// GrantDuration is the default window, in seconds, of a just-in-time grant
+	GrantDuration int `mapstructure:"grant_duration,omitempty"`
+	// MaxGrantDuration caps the window, in seconds, a request may ask for
+	MaxGrantDuration int `mapstructure:"max_grant_duration,omitempty"`
+	// Token selects how management tokens are validated
+	Token TokenConfig `mapstructure:"token,omitempty"`
+	// Resolvers add token types besides management tokens
+	Resolvers []ResolverConfig `mapstructure:"resolvers,omitempty"`
+	// MetricsResources are the resource prefixes reported as metric labels,
+	// other resources being reported as "other". Without prefixes the first
+	// MetricsLabelLimit resources and actions are reported as is.
+	MetricsResources []string `mapstructure:"metrics_resources,omitempty"`
+	// MetricsLabelLimit caps the distinct resource and action label values
+	MetricsLabelLimit int `mapstructure:"metrics_label_limit,omitempty"`
+	// ShutdownTimeout is how long, in seconds, in-flight RPCs may take to
+	// drain on shutdown
+	ShutdownTimeout int `mapstructure:"shutdown_timeout,omitempty"`
+	// Resources are the resource types and their actions. With any, requests
+	// and policies on other resources or actions are rejected.
+	Resources []ResourceTypeConfig `mapstructure:"resources,omitempty"`
+	// Lint tunes the checks of the policy linter
+	Lint policy.LintConfig `mapstructure:"lint,omitempty"`
+	// Tenants shard decisions by tenant, a customer for tokens
+	Tenants TenantConfig `mapstructure:"tenants,omitempty"`
 }
 
 // Server ...
 type Server struct {
-	connected bool
+	// connected is set while the server serves, read by handlers without
+	// a lock
+	connected int32
 	service   string
 	config    *Config
 	health    *health.Server
 	shutdown  chan struct{}
+	// started is closed once Start has set the server up, Reload waiting
+	// for it
+	started   chan struct{}
 	auth      auth.Client
+	db        *gorm.DB
+	resolvers map[string]identityResolver
+	// tracingEnabled adds spans around RPCs, token validation, policy load
+	// and enforcement
+	tracingEnabled bool
+	tracer         trace.Tracer
+	// resourceLabels and actionLabels bound metric label cardinality
+	resourceLabels *labelLimiter
+	actionLabels   *labelLimiter
+	// tls, tokenCache and logging are swapped by Reload
+	tls        *tlsStore
+	tokenCache *cachingValidator
+	logging    int32
+	// model is the text of the casbin model enforcers are created with
+	model   string
+	modelMu sync.RWMutex
+	// registry checks resources and actions against their types
+	registry *registry
+	// adminPolicy holds the grants the admin gate checks
+	adminPolicy *adminPolicy
+	// tenants holds the enforcers of decisions when tenants are enabled
+	tenants *tenantCache
+	// mu guards rpc and metrics, which Stop may read while Start runs, and
+	// the config fields Reload changes
+	mu      sync.Mutex
+	stopped bool
+	rpc     *grpc.Server
+	metrics *http.Server
+	// probes holds the last error of each dependency by health service
+	probes   map[string]error
+	probesMu sync.RWMutex
+	// background counts the goroutines stopped by closing shutdown
+	background sync.WaitGroup
+	stopOnce   sync.Once
 	accesspb.UnimplementedAccessServer
 }
 
 // New ...
 func New(config *Config, options ...Option) *Server {
+	config.SetDefaults()
 	logger.Println("Create server :", config)
 
 	opts := newOptions()
@@ -72,11 +157,27 @@ func New(config *Config, options ...Option) *Server {
 		opt(opts)
 	}
 
+	var tenants *tenantCache
+	if config.Tenants.Enabled {
+		tenants = newTenantCache(&config.Tenants)
+	}
+
 	return &Server{config: config,
 		health:   health.NewServer(),
 		shutdown: make(chan struct{}),
+		started:  make(chan struct{}),
 		service:  "access",
 		auth:     opts.auth,
+		registry: &registry{},
+		tenants:  tenants,
+
+		adminPolicy: &adminPolicy{},
+
+		tracingEnabled: opts.tracingEnabled,
+		tracer:         newTracer(opts.tracingEnabled),
+
+		resourceLabels: newLabelLimiter(config.MetricsResources, config.MetricsLabelLimit),
+		actionLabels:   newLabelLimiter(nil, config.MetricsLabelLimit),
 	}
 }
 
@@ -87,6 +188,40 @@ func DefaultConfig() *Config {
 		Metrics:  5053,
 		Logging:  true,
 		Recovery: true,
+		Model:    modelPath,
+
+		GrantDuration:    3600,
+		MaxGrantDuration: 8 * 3600,
+		ShutdownTimeout:  30,
+
+		MetricsLabelLimit: 100,
+		Limits: LimitConfig{
+			Callers:       10000,
+			MinConcurrent: 8,
+		},
+		Admin: AdminConfig{
+			RPCs: []string{
+				"ApproveAccess", "RejectAccess", "ListAccessRequests", "ListPermissions", "ListSubjects",
+				"ListPolicies", "AddPolicy", "RemovePolicy", "ListRoles", "AssignRole", "UnassignRole",
+				"ExportPolicies", "ImportPolicies", "WhatIf",
+				"ListResourceTypes", "PutResourceType", "DeleteResourceType",
+				"AddPolicies", "RemovePolicies", "ReplacePolicies",
+			},
+		},
+		Token: TokenConfig{
+			Mode:             TokenModeRemote,
+			Subjects:         []string{DefaultSubject},
+			CacheTTL:         300,
+			NegativeCacheTTL: 30,
+			CacheSize:        10000,
+		},
+		Tenants: TenantConfig{
+			Separator: "/",
+			Depth:     1,
+			Domain:    "{customer_id}",
+			Size:      1000,
+			TTL:       30,
+		},
 	}
 }
 
@@ -96,20 +231,33 @@ func (config Config) String() string {
 
 // Health check
 func (server *Server) Health(ctx context.Context) error {
-	if !server.connected {
+	if !server.isConnected() {
 		logger.Println(ErrServerNotConnected.Error())
 		return ErrServerNotConnected
 	}
+	for _, dep := range server.dependencies() {
+		if err := server.dependencyError(dep.service); err != nil {
+			return fmt.Errorf("%s: %v", dep.service, err)
+		}
+	}
 	return nil
 }
 
 func (server *Server) watch() {
 	ticker := time.NewTicker(time.Second * 5)
 	server.health.SetServingStatus(server.service, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	for _, dep := range server.dependencies() {
+		server.health.SetServingStatus(dep.service, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	}
+	server.background.Add(1)
 	go func() {
+		defer server.background.Done()
+		defer ticker.Stop()
+		server.probe()
 		for {
 			select {
 			case <-ticker.C:
+				server.probe()
 				status := grpc_health_v1.HealthCheckResponse_SERVING
 				if err := server.Health(context.TODO()); err != nil {
 					status = grpc_health_v1.HealthCheckResponse_NOT_SERVING
@@ -122,61 +270,182 @@ func (server *Server) watch() {
 	}()
 }
 
+// reap revokes just-in-time grants once their window closes
+func (server *Server) reap() {
+	ticker := time.NewTicker(time.Second * 30)
+	server.background.Add(1)
+	go func() {
+		defer server.background.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := server.expireGrants(context.TODO()); err != nil {
+					logger.Println("Error!!!Failed to expire grants:", err)
+				}
+			case <-server.shutdown:
+				return
+			}
+		}
+	}()
+}
+
+// OpenPolicyStore opens the database holding casbin_rule, creating it through
+// the adapter if needed
+func OpenPolicyStore() (*gorm.DB, error) {
+	a, err := gormadapter.NewAdapter("mysql", datasource())
+	if err != nil {
+		return nil, err
+	}
+	if err := a.Close(); err != nil {
+		return nil, err
+	}
+	return gorm.Open(mysql.Open(datasource()+databaseName), &gorm.Config{})
+}
+
+// openDatabase opens the policy store and migrates the tables owned by the
+// server
+func (server *Server) openDatabase() error {
+	db, err := OpenPolicyStore()
+	if err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&accessRequest{}, &storedResourceType{}); err != nil {
+		return err
+	}
+	server.db = db
+	return nil
+}
+
 // Start start server
 func (server *Server) Start(ctx context.Context) error {
 	var options []grpc.ServerOption
 
 	if server.config.Cert != "" && server.config.Key != "" {
-		creds, err := credentials.NewServerTLSFromFile(server.config.Cert, server.config.Key)
+		tlsConfig, err := serverTLSConfig(server.config)
 		if err != nil {
 			logger.Println("Error!!!Failed to load cert:", err)
 			return err
 		}
-		options = append(options, grpc.Creds(creds))
+		// handshakes read the store, so Reload rotates certificates
+		server.tls = &tlsStore{}
+		server.tls.set(tlsConfig)
+		options = append(options, grpc.Creds(credentials.NewTLS(&tls.Config{
+			GetConfigForClient: server.tls.get,
+		})))
 
 	}
-
-	if err := server.auth.Connect(context.TODO()); err != nil {
-		logger.Println("Error: ", err)
+	if server.config.ClientAuth != ClientAuthNone && server.config.Cert == "" {
+		err := errors.New("client auth needs a server cert and key")
+		logger.Println("Error!!!Failed to load cert:", err)
 		return err
 	}
-	if err := server.auth.Ping(context.TODO()); err != nil {
-		logger.Println("Error: ", err)
+	callers, err := newCallerGate(server.config.Callers)
+	if err != nil {
+		logger.Println("Error!!!Failed to setup callers:", err)
 		return err
 	}
 
-	var streamInterceptor []grpc.StreamServerInterceptor
-	var unaryInterceptor []grpc.UnaryServerInterceptor
-	if server.config.Metrics > 0 {
-		streamInterceptor = append(streamInterceptor, grpc_prometheus.StreamServerInterceptor)
-		unaryInterceptor = append(unaryInterceptor, grpc_prometheus.UnaryServerInterceptor)
+	tokens, err := newTokenValidator(&server.config.Token, server.auth)
+	if err != nil {
+		logger.Println("Error!!!Failed to setup token validation:", err)
+		return err
 	}
-	if server.config.Logging {
-		grpcLoggingOpts := []grpc_logrus.Option{
-			grpc_logrus.WithDecider(func(methodFullName string, err error) bool {
-				// will not log gRPC calls if it was a call to healthcheck and no error was raised
-				if err == nil && methodFullName == "/grpc.health.v1.Health/Check" {
-					return false
-				}
-				// by default you will log all calls
-				return true
-			}),
+	// the cache is always in place, Reload may turn it on later
+	server.tokenCache = newCachingValidator(tokens, &server.config.Token)
+	server.tokenCache.setEnabled(server.config.Caching)
+	tokens = server.tokenCache
+	resolvers, err := newIdentityResolvers(server.config.Resolvers, &server.config.Token, tokens)
+	if err != nil {
+		logger.Println("Error!!!Failed to setup identity resolvers:", err)
+		return err
+	}
+	server.resolvers = resolvers
+	static, err := staticTypes(server.config.Resources)
+	if err != nil {
+		logger.Println("Error!!!Failed to setup resource types:", err)
+		return err
+	}
+	server.registry.setStatic(static)
+
+	// the auth service is not needed when every token is verified locally
+	if server.config.Token.Mode != TokenModeLocal {
+		if err := server.auth.Connect(context.TODO()); err != nil {
+			logger.Println("Error: ", err)
+			return err
 		}
-		streamInterceptor = append(streamInterceptor, grpc_ctxtags.StreamServerInterceptor())
-		streamInterceptor = append(streamInterceptor, grpc_logrus.StreamServerInterceptor(logrus.NewEntry(logrus.New()), grpcLoggingOpts...))
-		unaryInterceptor = append(unaryInterceptor, grpc_ctxtags.UnaryServerInterceptor())
-		unaryInterceptor = append(unaryInterceptor, grpc_logrus.UnaryServerInterceptor(logrus.NewEntry(logrus.New()), grpcLoggingOpts...))
+		if err := server.auth.Ping(context.TODO()); err != nil {
+			logger.Println("Error: ", err)
+			return err
+		}
+	}
+
+	if err := server.openDatabase(); err != nil {
+		logger.Println("Error!!!Failed to open database:", err)
+		return err
+	}
+
+	model, err := policy.LoadModel(server.config.Model)
+	if err != nil {
+		logger.Println("Error!!!Failed to load model:", err)
+		return err
 	}
+	server.model = model
+
+	var streamInterceptor []grpc.StreamServerInterceptor
+	var unaryInterceptor []grpc.UnaryServerInterceptor
+	// recovery is outermost so a panic anywhere in the chain, gates
+	// included, fails only its call
 	if server.config.Recovery {
 		// Shared options for the logger, with a custom gRPC code to log level function.
 		grpcRecoveryOpts := []grpc_recovery.Option{
 			grpc_recovery.WithRecoveryHandler(func(p interface{}) (err error) {
+				logger.Printf("Error!!!Recovered from panic: %v", p)
 				return status.Errorf(codes.Unknown, "panic triggered: %v", p)
 			}),
 		}
 		streamInterceptor = append(streamInterceptor, grpc_recovery.StreamServerInterceptor(grpcRecoveryOpts...))
 		unaryInterceptor = append(unaryInterceptor, grpc_recovery.UnaryServerInterceptor(grpcRecoveryOpts...))
 	}
+	if server.config.Metrics > 0 {
+		streamInterceptor = append(streamInterceptor, grpc_prometheus.StreamServerInterceptor)
+		unaryInterceptor = append(unaryInterceptor, grpc_prometheus.UnaryServerInterceptor)
+	}
+	if server.tracingEnabled {
+		streamInterceptor = append(streamInterceptor, otelgrpc.StreamServerInterceptor())
+		unaryInterceptor = append(unaryInterceptor, otelgrpc.UnaryServerInterceptor())
+	}
+
+	// logging is always in place, Reload may turn it on later. It comes
+	// before the gates so the calls they reject are logged too.
+	server.setLogging(server.config.Logging)
+	grpcLoggingOpts := []grpc_logrus.Option{
+		grpc_logrus.WithDecider(func(methodFullName string, err error) bool {
+			// will not log gRPC calls if it was a call to healthcheck and no error was raised
+			if err == nil && methodFullName == "/grpc.health.v1.Health/Check" {
+				return false
+			}
+			// by default you will log all calls
+			return true
+		}),
+	}
+	streamInterceptor = append(streamInterceptor, server.streamIfLogging(grpc_ctxtags.StreamServerInterceptor()))
+	streamInterceptor = append(streamInterceptor, server.streamIfLogging(grpc_logrus.StreamServerInterceptor(logrus.NewEntry(logrus.New()), grpcLoggingOpts...)))
+	unaryInterceptor = append(unaryInterceptor, server.unaryIfLogging(grpc_ctxtags.UnaryServerInterceptor()))
+	unaryInterceptor = append(unaryInterceptor, server.unaryIfLogging(grpc_logrus.UnaryServerInterceptor(logrus.NewEntry(logrus.New()), grpcLoggingOpts...)))
+
+	// limits and callers are checked once the failure can be traced, counted
+	// and logged, and before any work is done for the call
+	limits := newLoadGate(&server.config.Limits)
+	streamInterceptor = append(streamInterceptor, limits.streamInterceptor)
+	unaryInterceptor = append(unaryInterceptor, limits.unaryInterceptor)
+	streamInterceptor = append(streamInterceptor, callers.streamInterceptor)
+	unaryInterceptor = append(unaryInterceptor, callers.unaryInterceptor)
+	if server.config.Admin.Enabled {
+		admin := newAdminGate(server, &server.config.Admin)
+		streamInterceptor = append(streamInterceptor, admin.streamInterceptor)
+		unaryInterceptor = append(unaryInterceptor, admin.unaryInterceptor)
+	}
 	if len(streamInterceptor) > 0 {
 		options = append(options, grpc_middleware.WithStreamServerChain(streamInterceptor...))
 	}
@@ -187,6 +456,7 @@ func (server *Server) Start(ctx context.Context) error {
 	grpcServer := grpc.NewServer(options...)
 
 	server.watch()
+	server.reap()
 	logger.Println("Start server on port", server.config.Port)
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", server.config.Port))
 	if err != nil {
@@ -196,18 +466,40 @@ func (server *Server) Start(ctx context.Context) error {
 	accesspb.RegisterAccessServer(grpcServer, server)
 
 	grpc_health_v1.RegisterHealthServer(grpcServer, server.health)
+	var metrics *http.Server
 	if server.config.Metrics > 0 {
+		grpc_prometheus.Register(grpcServer)
+		grpc_prometheus.EnableHandlingTimeHistogram()
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		mux.HandleFunc("/healthz", server.serveLiveness)
+		mux.HandleFunc("/readyz", server.serveReadiness)
+		metrics = &http.Server{
+			Addr:    fmt.Sprintf(":%d", server.config.Metrics),
+			Handler: mux,
+		}
+	}
+
+	server.mu.Lock()
+	if server.stopped {
+		server.mu.Unlock()
+		lis.Close()
+		return nil
+	}
+	server.rpc = grpcServer
+	server.metrics = metrics
+	server.mu.Unlock()
+	close(server.started)
+
+	if metrics != nil {
 		go func() {
-			grpc_prometheus.Register(grpcServer)
-			grpc_prometheus.EnableHandlingTimeHistogram()
-			http.Handle("/metrics", promhttp.Handler())
 			logger.Println("Starting metrics server : ", server.config.Metrics)
-			if err := http.ListenAndServe(fmt.Sprintf(":%d", server.config.Metrics), nil); err != nil {
+			if err := metrics.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 				logger.Fatalf("Error in metrics server > %v", err)
 			}
 		}()
 	}
-	server.connected = true
+	server.setConnected(true)
 	if err := grpcServer.Serve(lis); err != nil {
 		logger.Println("Error!!!Failed to start server", err)
 		return err
@@ -215,3 +507,59 @@ func (server *Server) Start(ctx context.Context) error {
 
 	return nil
 }
+
+// Stop shuts the server down: health turns NOT_SERVING first so no new
+// traffic is routed here, in-flight RPCs drain until ctx is done, after which
+// they are cancelled, then the metrics server, background goroutines and the
+// database are stopped.
+func (server *Server) Stop(ctx context.Context) error {
+	var err error
+	server.stopOnce.Do(func() {
+		logger.Println("Stopping server")
+		server.health.Shutdown()
+
+		server.mu.Lock()
+		server.stopped = true
+		rpc, metrics := server.rpc, server.metrics
+		server.mu.Unlock()
+
+		if rpc != nil {
+			drained := make(chan struct{})
+			go func() {
+				rpc.GracefulStop()
+				close(drained)
+			}()
+			select {
+			case <-drained:
+			case <-ctx.Done():
+				logger.Println("Error!!!Shutdown deadline reached, cancelling in-flight RPCs")
+				rpc.Stop()
+				<-drained
+			}
+		}
+		server.setConnected(false)
+
+		if metrics != nil {
+			if e := metrics.Shutdown(ctx); e != nil {
+				logger.Println("Error!!!Failed to stop metrics server:", e)
+				err = e
+			}
+		}
+
+		close(server.shutdown)
+		server.background.Wait()
+
+		if server.db != nil {
+			sqlDB, e := server.db.DB()
+			if e == nil {
+				e = sqlDB.Close()
+			}
+			if e != nil {
+				logger.Println("Error!!!Failed to close database:", e)
+				err = e
+			}
+		}
+		logger.Println("Server stopped")
+	})
+	return err
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultSubject is the subject template of management tokens
+	DefaultSubject = "{user_id}_{customer_id}"
+
+	roleAttribute = "role"
+)
+
+// subjectTemplate renders a subject from token attributes named in braces,
+// e.g. "user:{customer_id}/{user_id}"
+type subjectTemplate string
+
+func (t subjectTemplate) render(lookup func(name string) (string, bool)) (string, error) {
+	var b strings.Builder
+	s := string(t)
+	for {
+		open := strings.IndexByte(s, '{')
+		if open < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(s[open:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated placeholder in subject %q", string(t))
+		}
+		name := s[open+1 : open+end]
+		value, ok := lookup(name)
+		if !ok || value == "" {
+			return "", status.Errorf(codes.Unauthenticated, "token has no %s for subject %q", name, string(t))
+		}
+		b.WriteString(s[:open])
+		b.WriteString(value)
+		s = s[open+end+1:]
+	}
+}
+
+// subjectMapper derives every subject a token stands for: one per subject
+// template and one per role the token declares
+type subjectMapper struct {
+	subjects []subjectTemplate
+	role     subjectTemplate
+}
+
+// newSubjectMapper checks the templates use only the attributes tokens have,
+// any attribute when nil, the role template {role} too; an empty role
+// template ignores the roles declared by tokens
+func newSubjectMapper(subjects []string, role string, attributes []string) (*subjectMapper, error) {
+	if len(subjects) == 0 {
+		return nil, fmt.Errorf("at least one subject template is required")
+	}
+	m := &subjectMapper{role: subjectTemplate(role)}
+	for _, subject := range subjects {
+		if err := subjectTemplate(subject).check(attributes); err != nil {
+			return nil, err
+		}
+		m.subjects = append(m.subjects, subjectTemplate(subject))
+	}
+	if attributes != nil {
+		attributes = append(append([]string(nil), attributes...), roleAttribute)
+	}
+	if err := m.role.check(attributes); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// check renders the template, failing on a placeholder not among attributes
+// unless nil
+func (t subjectTemplate) check(attributes []string) error {
+	var unknown string
+	_, err := t.render(func(name string) (string, bool) {
+		if attributes == nil {
+			return "-", true
+		}
+		for _, attribute := range attributes {
+			if name == attribute {
+				return "-", true
+			}
+		}
+		unknown = name
+		return "", false
+	})
+	if unknown != "" {
+		return fmt.Errorf("subject %q uses {%s}, which tokens do not have, only %s", string(t), unknown, strings.Join(attributes, ", "))
+	}
+	return err
+}
+
+func (m *subjectMapper) apply(lookup func(name string) (string, bool), roles []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var subjects []string
+	add := func(t subjectTemplate, lookup func(name string) (string, bool)) error {
+		subject, err := t.render(lookup)
+		if err != nil {
+			return err
+		}
+		if !seen[subject] {
+			seen[subject] = true
+			subjects = append(subjects, subject)
+		}
+		return nil
+	}
+
+	for _, t := range m.subjects {
+		if err := add(t, lookup); err != nil {
+			return nil, err
+		}
+	}
+	if m.role == "" {
+		return subjects, nil
+	}
+	for _, role := range roles {
+		role := role
+		err := add(m.role, func(name string) (string, bool) {
+			if name == roleAttribute {
+				return role, true
+			}
+			return lookup(name)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return subjects, nil
+}
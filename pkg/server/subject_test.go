@@ -0,0 +1,143 @@
+package server
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewSubjectMapper(t *testing.T) {
+	tests := []struct {
+		name       string
+		subjects   []string
+		role       string
+		attributes []string
+		wantErr    string
+	}{
+		{name: "default", subjects: []string{DefaultSubject}, attributes: managementAttributes},
+		{
+			name:       "roles",
+			subjects:   []string{"user:{customer_id}/{user_id}", "customer:{customer_id}"},
+			role:       "role:{customer_id}/{role}",
+			attributes: managementAttributes,
+		},
+		{name: "no templates", attributes: managementAttributes, wantErr: "at least one"},
+		{name: "unknown attribute", subjects: []string{"{org}/{user_id}"}, attributes: managementAttributes, wantErr: "{org}"},
+		{name: "role outside the role template", subjects: []string{"{role}"}, attributes: managementAttributes, wantErr: "{role}"},
+		{name: "unknown attribute in the role template", subjects: []string{"{user_id}"}, role: "{org}/{role}", attributes: managementAttributes, wantErr: "{org}"},
+		{name: "unterminated", subjects: []string{"user:{user_id"}, attributes: managementAttributes, wantErr: "unterminated"},
+		{name: "any claim", subjects: []string{"{org}/{sub}"}, role: "{org}/{role}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newSubjectMapper(tt.subjects, tt.role, tt.attributes)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("newSubjectMapper() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("newSubjectMapper() = %v, want an error with %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewIdentityResolversRejectsUnknownAttributes(t *testing.T) {
+	token := &TokenConfig{Subjects: []string{"{org}:{user_id}"}}
+	if _, err := newIdentityResolvers(nil, token, nil); err == nil {
+		t.Error("newIdentityResolvers() accepted a management subject using {org}")
+	}
+}
+
+// attributes looks up a fixed set of token attributes
+func attributes(values map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := values[name]
+		return v, ok
+	}
+}
+
+func TestSubjectTemplateRender(t *testing.T) {
+	lookup := attributes(map[string]string{"user_id": "u1", "customer_id": "c1", "empty": ""})
+	tests := []struct {
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{template: DefaultSubject, want: "u1_c1"},
+		{template: "user:{customer_id}/{user_id}", want: "user:c1/u1"},
+		{template: "static", want: "static"},
+		{template: "{user_id}{user_id}", want: "u1u1"},
+		{template: "{missing}", wantErr: true},
+		{template: "{empty}", wantErr: true},
+		{template: "{user_id", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := subjectTemplate(tt.template).render(lookup)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("render(%q) = %q, %v, want %q", tt.template, got, err, tt.want)
+		}
+	}
+}
+
+func TestSubjectMapperApply(t *testing.T) {
+	lookup := attributes(map[string]string{"user_id": "u1", "customer_id": "c1"})
+	tests := []struct {
+		name     string
+		subjects []string
+		role     string
+		roles    []string
+		want     []string
+	}{
+		{
+			name:     "subjects",
+			subjects: []string{"user:{customer_id}/{user_id}", "customer:{customer_id}"},
+			want:     []string{"user:c1/u1", "customer:c1"},
+		},
+		{
+			name:     "roles",
+			subjects: []string{"{user_id}"},
+			role:     "role:{customer_id}/{role}",
+			roles:    []string{"host", "guest"},
+			want:     []string{"u1", "role:c1/host", "role:c1/guest"},
+		},
+		{
+			name:     "roles ignored without a role template",
+			subjects: []string{"{user_id}"},
+			roles:    []string{"host"},
+			want:     []string{"u1"},
+		},
+		{
+			name:     "repeated subjects once",
+			subjects: []string{"{user_id}", "{user_id}"},
+			role:     "{role}",
+			roles:    []string{"u1", "host", "host"},
+			want:     []string{"u1", "host"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := newSubjectMapper(tt.subjects, tt.role, managementAttributes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := m.apply(lookup, tt.roles)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("apply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	m, err := newSubjectMapper([]string{"{user_id}"}, "", managementAttributes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.apply(attributes(map[string]string{"customer_id": "c1"}), nil); err == nil {
+		t.Error("apply() of a token without a user_id succeeded")
+	}
+}
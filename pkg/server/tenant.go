@@ -0,0 +1,309 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/piyush1104/access/pkg/policy"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// TenantConfig shards Authorize and AuthorizeToken by tenant: each tenant is
+// decided by its own enforcer, holding only the policy on the resources of
+// the tenant, which is loaded, locked and reloaded independently of the
+// others. Roles are loaded whole, being shared by tenants.
+type TenantConfig struct {
+	Enabled bool `mapstructure:"enabled,omitempty"`
+	// Separator and Depth cut the tenant of a resource: with "/" and 1,
+	// customer-1/room/5 belongs to customer-1
+	Separator string `mapstructure:"separator,omitempty"`
+	Depth     int    `mapstructure:"depth,omitempty"`
+	// Domain is the template, over {customer_id}, of the tenant of the
+	// customer of a token, whose enforcer decides for the token on the
+	// resources in it. Other resources, e.g. data, and tokens without a
+	// customer are decided in the tenant of the resource, by its policy.
+	Domain string `mapstructure:"domain,omitempty"`
+	// Size bounds the tenant enforcers kept, the least recently used being
+	// dropped first
+	Size int `mapstructure:"size,omitempty"`
+	// TTL is how long, in seconds, a tenant policy is used before it is
+	// reloaded in the background, so changes made through other servers are
+	// seen
+	TTL int `mapstructure:"ttl,omitempty"`
+}
+
+// tenantLoader loads the enforcer of a tenant, reporting the estimated bytes
+// of its rules
+type tenantLoader func(ctx context.Context) (*casbin.Enforcer, int, error)
+
+// tenantCache keeps an enforcer per tenant, loaded on first use. The cache
+// mutex only guards which tenants are kept, each entry locking itself.
+type tenantCache struct {
+	separator string
+	depth     int
+	domain    string
+	size      int
+	ttl       time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*tenantEntry
+	bytes   int64
+}
+
+type tenantEntry struct {
+	tenant string
+	// load lets one request at a time load the tenant the first time
+	load sync.Mutex
+
+	mu        sync.RWMutex
+	e         *casbin.Enforcer
+	loaded    time.Time
+	bytes     int
+	reloading bool
+	// dropped entries are no longer counted nor reloaded
+	dropped bool
+
+	// seen is guarded by the cache mutex
+	seen time.Time
+}
+
+func newTenantCache(config *TenantConfig) *tenantCache {
+	return &tenantCache{
+		separator: config.Separator,
+		depth:     config.Depth,
+		domain:    config.Domain,
+		size:      config.Size,
+		ttl:       time.Duration(config.TTL) * time.Second,
+		entries:   make(map[string]*tenantEntry),
+	}
+}
+
+// tenantOf cuts the tenant of resource
+func (c *tenantCache) tenantOf(resource string) string {
+	parts := strings.SplitN(resource, c.separator, c.depth+1)
+	if len(parts) > c.depth {
+		parts = parts[:c.depth]
+	}
+	return strings.Join(parts, c.separator)
+}
+
+// customerTenant is the tenant of the resources of customer
+func (c *tenantCache) customerTenant(customer string) string {
+	return strings.ReplaceAll(c.domain, "{customer_id}", customer)
+}
+
+// inTenant tells whether resource is tenant or under it
+func (c *tenantCache) inTenant(resource, tenant string) bool {
+	return resource == tenant || strings.HasPrefix(resource, tenant+c.separator)
+}
+
+// filter selects the policy of tenant
+func (c *tenantCache) filter(tenant string) policy.Filter {
+	return policy.Filter{Resource: tenant, Prefix: tenant + c.separator}
+}
+
+// get returns the enforcer of tenant. The first request loads it; once it is
+// older than the TTL it is reloaded in the background while requests keep
+// using it.
+func (c *tenantCache) get(ctx context.Context, tenant string, now time.Time, load tenantLoader) (*casbin.Enforcer, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[tenant]
+	if !ok {
+		if len(c.entries) >= c.size {
+			c.evict()
+		}
+		entry = &tenantEntry{tenant: tenant}
+		c.entries[tenant] = entry
+		tenantCacheEntries.Set(float64(len(c.entries)))
+	}
+	entry.seen = now
+	c.mu.Unlock()
+
+	entry.mu.RLock()
+	e, loaded := entry.e, entry.loaded
+	entry.mu.RUnlock()
+	if e != nil {
+		if now.Sub(loaded) < c.ttl {
+			tenantCacheRequests.WithLabelValues("hit").Inc()
+		} else {
+			tenantCacheRequests.WithLabelValues("expired").Inc()
+			c.reload(entry, load)
+		}
+		return e, nil
+	}
+
+	entry.load.Lock()
+	defer entry.load.Unlock()
+	// another request may have loaded it meanwhile
+	entry.mu.RLock()
+	e = entry.e
+	entry.mu.RUnlock()
+	if e != nil {
+		tenantCacheRequests.WithLabelValues("hit").Inc()
+		return e, nil
+	}
+	tenantCacheRequests.WithLabelValues("miss").Inc()
+	e, bytes, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.store(entry, e, bytes)
+	return e, nil
+}
+
+// reload loads entry again in the background, unless it already is
+func (c *tenantCache) reload(entry *tenantEntry, load tenantLoader) {
+	entry.mu.Lock()
+	if entry.reloading || entry.dropped {
+		entry.mu.Unlock()
+		return
+	}
+	entry.reloading = true
+	entry.mu.Unlock()
+
+	go func() {
+		e, bytes, err := load(context.Background())
+		if err != nil {
+			logger.Println("Error!!!Failed to reload tenant", entry.tenant, ":", err)
+			entry.mu.Lock()
+			entry.reloading = false
+			entry.mu.Unlock()
+			return
+		}
+		c.store(entry, e, bytes)
+	}()
+}
+
+func (c *tenantCache) store(entry *tenantEntry, e *casbin.Enforcer, bytes int) {
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if !entry.dropped {
+		tenantPolicyBytes.Set(float64(atomic.AddInt64(&c.bytes, int64(bytes-entry.bytes))))
+	}
+	entry.e, entry.loaded, entry.bytes, entry.reloading = e, time.Now(), bytes, false
+}
+
+// drop forgets entry, under the cache mutex
+func (c *tenantCache) drop(entry *tenantEntry) {
+	delete(c.entries, entry.tenant)
+	entry.mu.Lock()
+	entry.dropped = true
+	tenantPolicyBytes.Set(float64(atomic.AddInt64(&c.bytes, -int64(entry.bytes))))
+	entry.mu.Unlock()
+}
+
+// evict drops the tenant seen least recently
+func (c *tenantCache) evict() {
+	var oldest *tenantEntry
+	for _, entry := range c.entries {
+		if oldest == nil || entry.seen.Before(oldest.seen) {
+			oldest = entry
+		}
+	}
+	if oldest == nil {
+		return
+	}
+	c.drop(oldest)
+	tenantCacheEvictions.Inc()
+}
+
+// invalidate drops the tenants holding any of resources, so the next
+// decisions in them see a policy change while other tenants are left alone
+func (c *tenantCache) invalidate(resources map[string]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, entry := range c.entries {
+		for resource := range resources {
+			if c.inTenant(resource, entry.tenant) {
+				c.drop(entry)
+				break
+			}
+		}
+	}
+	tenantCacheEntries.Set(float64(len(c.entries)))
+}
+
+// purge drops every tenant, so the next decisions see a policy change
+func (c *tenantCache) purge() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, entry := range c.entries {
+		c.drop(entry)
+	}
+	tenantCacheEntries.Set(0)
+}
+
+// decisionEnforcer is the enforcer deciding on resource for customer, when
+// known: with tenants enabled, the one of the customer tenant when resource
+// is in it, else the one of the tenant of resource; without, one with the
+// full policy
+func (server *Server) decisionEnforcer(ctx context.Context, resource, customer string) (*casbin.Enforcer, error) {
+	if server.tenants == nil {
+		return server.getEnforcer(ctx)
+	}
+	tenant := server.tenants.tenantOf(resource)
+	if customer != "" {
+		if own := server.tenants.customerTenant(customer); server.tenants.inTenant(resource, own) {
+			tenant = own
+		}
+	}
+	filter := server.tenants.filter(tenant)
+	return server.tenants.get(ctx, tenant, time.Now(), func(ctx context.Context) (*casbin.Enforcer, int, error) {
+		return server.loadTenant(ctx, tenant, filter)
+	})
+}
+
+// loadTenant creates an enforcer with the policy filter selects, reporting
+// the estimated bytes of its rules
+func (server *Server) loadTenant(ctx context.Context, tenant string, filter policy.Filter) (e *casbin.Enforcer, bytes int, err error) {
+	ctx, span := server.startSpan(ctx, "casbin.LoadFilteredPolicy", attribute.String("access.tenant", tenant))
+	defer func() { endSpan(span, err) }()
+
+	m, err := server.newModel()
+	if err != nil {
+		return nil, 0, err
+	}
+	a := policy.NewFilteredAdapter(server.db.WithContext(ctx))
+	e, err = casbin.NewEnforcer(m, a)
+	if err != nil {
+		return nil, 0, err
+	}
+	start := time.Now()
+	if err = e.LoadFilteredPolicy(filter); err != nil {
+		return nil, 0, err
+	}
+	observeSince(tenantPolicyLoadDuration, start)
+	rules, bytes := a.Loaded()
+	span.SetAttributes(attribute.Int("casbin.rules", rules), attribute.Int("casbin.bytes", bytes))
+	return e, bytes, nil
+}
+
+// policyChanged drops the tenants the changed rules are on: those holding the
+// resources of p rules, or every tenant for g links since roles span them
+func (server *Server) policyChanged(rules ...policy.Rule) {
+	for _, rule := range rules {
+		if !strings.HasPrefix(rule.PType, "p") || len(rule.Values) < 2 || strings.HasPrefix(rule.Values[1], adminNamespace) {
+			server.adminPolicy.reset()
+			break
+		}
+	}
+	if server.tenants == nil {
+		return
+	}
+	resources := make(map[string]bool)
+	for _, rule := range rules {
+		if !strings.HasPrefix(rule.PType, "p") || len(rule.Values) < 2 {
+			server.tenants.purge()
+			return
+		}
+		resources[rule.Values[1]] = true
+	}
+	server.tenants.invalidate(resources)
+}
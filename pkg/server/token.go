@@ -0,0 +1,341 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/100mslive/auth"
+	"github.com/golang-jwt/jwt/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// TokenModeRemote validates every token with the auth service
+	TokenModeRemote = "remote"
+	// TokenModeLocal verifies tokens against the configured keys only
+	TokenModeLocal = "local"
+	// TokenModeFallback verifies tokens locally and asks the auth service
+	// about tokens signed by a key it does not know
+	TokenModeFallback = "fallback"
+
+	managementTokenType = "management"
+)
+
+var errUnknownKey = errors.New("no key to verify token")
+
+// TokenConfig selects how management tokens are validated
+type TokenConfig struct {
+	Mode string `mapstructure:"mode,omitempty"`
+	// Secret verifies HMAC signed tokens
+	Secret string `mapstructure:"secret,omitempty"`
+	// PublicKey is a PEM file verifying RSA or ECDSA tokens without a kid
+	PublicKey string `mapstructure:"public_key,omitempty"`
+	// JWKS is a JSON Web Key Set file verifying tokens by kid
+	JWKS   string `mapstructure:"jwks,omitempty"`
+	Issuer string `mapstructure:"issuer,omitempty"`
+	// Subjects are the templates, over {user_id} and {customer_id}, of the
+	// subjects a token stands for. Defaults to DefaultSubject.
+	Subjects []string `mapstructure:"subjects,omitempty"`
+	// RoleSubject is the template, over {role}, of the subject each role in
+	// the roles claim maps to. Roles are only known for locally verified
+	// tokens and ignored when empty.
+	RoleSubject string `mapstructure:"role_subject,omitempty"`
+	// CacheTTL caps, in seconds, how long a valid token is cached when the
+	// server has caching enabled; tokens are never cached past their exp
+	CacheTTL int `mapstructure:"cache_ttl,omitempty"`
+	// NegativeCacheTTL is how long, in seconds, a rejected token is cached
+	NegativeCacheTTL int `mapstructure:"negative_cache_ttl,omitempty"`
+	// CacheSize bounds the number of cached tokens
+	CacheSize int `mapstructure:"cache_size,omitempty"`
+}
+
+// tokenIdentity is what a validated management token says about its holder
+type tokenIdentity struct {
+	CustomerID string
+	UserID     string
+	Roles      []string
+}
+
+type tokenValidator interface {
+	validate(ctx context.Context, token string) (*tokenIdentity, error)
+}
+
+func newTokenValidator(config *TokenConfig, client auth.Client) (tokenValidator, error) {
+	remote := &remoteValidator{auth: client}
+	switch config.Mode {
+	case "", TokenModeRemote:
+		return remote, nil
+	case TokenModeLocal, TokenModeFallback:
+	default:
+		return nil, fmt.Errorf("unknown token mode %q", config.Mode)
+	}
+
+	local, err := newLocalValidator(config)
+	if err != nil {
+		return nil, err
+	}
+	if config.Mode == TokenModeFallback {
+		return &fallbackValidator{local: local, remote: remote}, nil
+	}
+	return local, nil
+}
+
+// remoteValidator asks the auth service
+type remoteValidator struct {
+	auth auth.Client
+}
+
+func (v *remoteValidator) validate(ctx context.Context, token string) (*tokenIdentity, error) {
+	res, err := v.auth.ValidateManagementToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenIdentity{
+		CustomerID: res.CustomerID,
+		UserID:     res.UserID,
+	}, nil
+}
+
+// fallbackValidator prefers local verification
+type fallbackValidator struct {
+	local  *localValidator
+	remote *remoteValidator
+}
+
+func (v *fallbackValidator) validate(ctx context.Context, token string) (*tokenIdentity, error) {
+	id, err := v.local.validate(ctx, token)
+	if errors.Is(err, errUnknownKey) {
+		return v.remote.validate(ctx, token)
+	}
+	return id, err
+}
+
+type managementClaims struct {
+	jwt.RegisteredClaims
+	Type       string   `json:"type,omitempty"`
+	CustomerID string   `json:"customer_id"`
+	UserID     string   `json:"user_id"`
+	Roles      []string `json:"roles,omitempty"`
+}
+
+// localValidator verifies token signatures and claims without the auth service
+type localValidator struct {
+	keys   *jwtKeys
+	issuer string
+}
+
+func newLocalValidator(config *TokenConfig) (*localValidator, error) {
+	keys, err := newJWTKeys(config.Secret, config.PublicKey, config.JWKS)
+	if err != nil {
+		return nil, err
+	}
+	return &localValidator{
+		keys:   keys,
+		issuer: config.Issuer,
+	}, nil
+}
+
+func (v *localValidator) validate(ctx context.Context, token string) (*tokenIdentity, error) {
+	claims := &managementClaims{}
+	if err := v.keys.parse(token, claims); err != nil {
+		return nil, err
+	}
+	if claims.Type != "" && claims.Type != managementTokenType {
+		return nil, status.Errorf(codes.Unauthenticated, "token type %q is not %s", claims.Type, managementTokenType)
+	}
+	if v.issuer != "" && !claims.VerifyIssuer(v.issuer, true) {
+		return nil, status.Error(codes.Unauthenticated, "token issuer mismatch")
+	}
+	if claims.CustomerID == "" || claims.UserID == "" {
+		return nil, status.Error(codes.Unauthenticated, "token has no customer_id or user_id")
+	}
+	return &tokenIdentity{
+		CustomerID: claims.CustomerID,
+		UserID:     claims.UserID,
+		Roles:      claims.Roles,
+	}, nil
+}
+
+// jwtKeys holds the keys verifying JWT signatures: an HMAC secret and a public
+// key for tokens without a kid, and a key set for tokens with one
+type jwtKeys struct {
+	secret []byte
+	key    interface{}
+	keys   map[string]interface{}
+	parser *jwt.Parser
+}
+
+func newJWTKeys(secret, publicKey, jwks string) (*jwtKeys, error) {
+	k := &jwtKeys{
+		keys: make(map[string]interface{}),
+		parser: jwt.NewParser(jwt.WithValidMethods([]string{
+			"HS256", "HS384", "HS512",
+			"RS256", "RS384", "RS512",
+			"PS256", "PS384", "PS512",
+			"ES256", "ES384", "ES512",
+		})),
+	}
+	if secret != "" {
+		k.secret = []byte(secret)
+	}
+	if publicKey != "" {
+		key, err := loadPublicKey(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		k.key = key
+	}
+	if jwks != "" {
+		keys, err := loadJWKS(jwks)
+		if err != nil {
+			return nil, err
+		}
+		k.keys = keys
+	}
+	if k.secret == nil && k.key == nil && len(k.keys) == 0 {
+		return nil, errors.New("local token validation needs a secret, public key or jwks")
+	}
+	return k, nil
+}
+
+// parse verifies the token signature and standard time claims into claims
+func (k *jwtKeys) parse(token string, claims jwt.Claims) error {
+	_, err := k.parser.ParseWithClaims(token, claims, k.keyFunc)
+	return err
+}
+
+func (k *jwtKeys) keyFunc(token *jwt.Token) (interface{}, error) {
+	var key interface{}
+	if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+		key = k.keys[kid]
+	} else if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		key = k.secret
+	} else {
+		key = k.key
+	}
+
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if secret, ok := key.([]byte); ok && len(secret) > 0 {
+			return secret, nil
+		}
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		if rsaKey, ok := key.(*rsa.PublicKey); ok {
+			return rsaKey, nil
+		}
+	case *jwt.SigningMethodECDSA:
+		if ecKey, ok := key.(*ecdsa.PublicKey); ok {
+			return ecKey, nil
+		}
+	}
+	return nil, errUnknownKey
+}
+
+func loadPublicKey(path string) (interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+	key, err := jwt.ParseECPublicKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: not an RSA or ECDSA public key", path)
+	}
+	return key, nil
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// loadJWKS reads the RSA, EC and symmetric keys of a key set by kid
+func loadJWKS(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kid == "" {
+			return nil, fmt.Errorf("%s: key without kid", path)
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("%s: key %s: %v", path, k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k *jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(k.K)
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/piyush1104/access/pkg/server"
+
+func newTracer(enabled bool) trace.Tracer {
+	if !enabled {
+		return trace.NewNoopTracerProvider().Tracer(tracerName)
+	}
+	return otel.Tracer(tracerName)
+}
+
+// startSpan starts a child span of the one in ctx
+func (server *Server) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return server.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records err, if any, on span and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"sort"
+
+	"github.com/100mslive/packages/log"
+	"github.com/casbin/casbin/v2"
+	accesspb "github.com/piyush1104/access/pkg/internal"
+	"github.com/piyush1104/access/pkg/policy"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxWhatIfProbes bounds the decisions one WhatIf evaluates
+const maxWhatIfProbes = 10000
+
+// WhatIf evaluates the decisions a proposed change would flip without
+// applying it: the requested probes, or else every decision of the subjects
+// the change touches, their members included, on the resources it touches
+func (server *Server) WhatIf(ctx context.Context, req *accesspb.WhatIfRequest) (*accesspb.WhatIfReply, error) {
+	if !server.isConnected() {
+		log.Errorf(ErrServerNotConnected.Error())
+		return nil, ErrServerNotConnected
+	}
+	if len(req.GetAdd()) == 0 && len(req.GetRemove()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "add or remove field is required")
+	}
+	diff := &policy.Diff{}
+	var err error
+	if diff.Add, err = whatIfRules("add", req.GetAdd()); err != nil {
+		return nil, err
+	}
+	if diff.Remove, err = whatIfRules("remove", req.GetRemove()); err != nil {
+		return nil, err
+	}
+	if err := server.checkRules(ctx, diff.Add); err != nil {
+		return nil, err
+	}
+	for i, probe := range req.GetProbes() {
+		if probe.GetSubject() == "" || probe.GetResource() == "" || probe.GetAction() == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "probe %d: subject, resource and action are required", i+1)
+		}
+	}
+	if len(req.GetProbes()) > maxWhatIfProbes {
+		return nil, status.Errorf(codes.InvalidArgument, "more than %d probes", maxWhatIfProbes)
+	}
+
+	before, err := server.getEnforcer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	after, err := policy.NewEnforcer(server.modelText(), diff.Patch(policy.FromEnforcer(before)))
+	if err != nil {
+		return nil, err
+	}
+
+	probes := req.GetProbes()
+	if len(probes) == 0 {
+		if probes, err = whatIfProbes(before, after, diff); err != nil {
+			return nil, err
+		}
+	}
+	reply := &accesspb.WhatIfReply{Probed: int32(len(probes))}
+	for _, probe := range probes {
+		was, err := before.Enforce(probe.Subject, probe.Resource, probe.Action)
+		if err != nil {
+			return nil, err
+		}
+		is, err := after.Enforce(probe.Subject, probe.Resource, probe.Action)
+		if err != nil {
+			return nil, err
+		}
+		if was != is {
+			reply.Changes = append(reply.Changes, &accesspb.DecisionChange{
+				Subject:  probe.Subject,
+				Resource: probe.Resource,
+				Action:   probe.Action,
+				Before:   was,
+				After:    is,
+			})
+		}
+	}
+	return reply, nil
+}
+
+func whatIfRules(field string, rules []*accesspb.PolicyRule) ([]policy.Rule, error) {
+	out := make([]policy.Rule, 0, len(rules))
+	for i, rule := range rules {
+		ptype := rule.GetPType()
+		if ptype == "" || (ptype[0] != 'p' && ptype[0] != 'g') {
+			return nil, status.Errorf(codes.InvalidArgument, "%s rule %d: unknown policy type %q", field, i+1, ptype)
+		}
+		if len(rule.GetValues()) == 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "%s rule %d: values field is required", field, i+1)
+		}
+		out = append(out, policy.Rule{PType: ptype, Values: rule.GetValues()})
+	}
+	return out, nil
+}
+
+// whatIfProbes lists the decisions a diff may flip: the subjects of its rules
+// and their members, before or after, on the resources and actions of its p
+// rules and of the roles its g links grant or revoke
+func whatIfProbes(before, after *casbin.Enforcer, diff *policy.Diff) ([]*accesspb.Probe, error) {
+	subjects := make(map[string]bool)
+	perms := make(map[[2]string]bool)
+	for _, rule := range append(append([]policy.Rule(nil), diff.Add...), diff.Remove...) {
+		subjects[rule.Values[0]] = true
+		switch {
+		case rule.PType[0] == 'p' && len(rule.Values) >= 3:
+			perms[[2]string{rule.Values[1], rule.Values[2]}] = true
+		case rule.PType[0] == 'g' && len(rule.Values) >= 2:
+			for _, e := range []*casbin.Enforcer{before, after} {
+				granted, err := e.GetImplicitPermissionsForUser(rule.Values[1])
+				if err != nil {
+					return nil, err
+				}
+				for _, p := range granted {
+					if len(p) >= 3 {
+						perms[[2]string{p[1], p[2]}] = true
+					}
+				}
+			}
+		}
+	}
+	touched := make([]string, 0, len(subjects))
+	for subject := range subjects {
+		touched = append(touched, subject)
+	}
+	for _, subject := range touched {
+		for _, e := range []*casbin.Enforcer{before, after} {
+			members, err := e.GetImplicitUsersForRole(subject)
+			if err != nil {
+				return nil, err
+			}
+			for _, member := range members {
+				subjects[member] = true
+			}
+		}
+	}
+
+	if n := len(subjects) * len(perms); n > maxWhatIfProbes {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"the change touches %d decisions, more than %d, probes field is required", n, maxWhatIfProbes)
+	}
+	names := make([]string, 0, len(subjects))
+	for subject := range subjects {
+		names = append(names, subject)
+	}
+	sort.Strings(names)
+	pairs := make([][2]string, 0, len(perms))
+	for pair := range perms {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+
+	probes := make([]*accesspb.Probe, 0, len(names)*len(pairs))
+	for _, subject := range names {
+		for _, pair := range pairs {
+			probes = append(probes, &accesspb.Probe{Subject: subject, Resource: pair[0], Action: pair[1]})
+		}
+	}
+	return probes, nil
+}
@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+const (
+	// ExporterNone disables tracing
+	ExporterNone = ""
+	// ExporterOTLP sends spans to an OTLP collector over gRPC
+	ExporterOTLP = "otlp"
+	// ExporterStdout prints spans, for local runs
+	ExporterStdout = "stdout"
+	// ExporterFile writes spans to File, for local runs
+	ExporterFile = "file"
+)
+
+// Config selects where spans are exported
+type Config struct {
+	// Exporter is otlp, stdout or file; tracing is off when empty
+	Exporter string `mapstructure:"exporter,omitempty"`
+	// Endpoint is the host:port of the OTLP collector
+	Endpoint string `mapstructure:"endpoint,omitempty"`
+	// Insecure disables TLS to the collector
+	Insecure bool `mapstructure:"insecure,omitempty"`
+	// File is the path spans are appended to by the file exporter
+	File string `mapstructure:"file,omitempty"`
+	// SampleRatio is the fraction of new traces sampled, traces started by
+	// callers following their sampling decision
+	SampleRatio float64 `mapstructure:"sample_ratio,omitempty"`
+}
+
+// DefaultConfig default config
+func DefaultConfig() *Config {
+	return &Config{
+		Endpoint:    "localhost:4317",
+		SampleRatio: 1,
+	}
+}
+
+// Enabled tells whether spans are exported
+func (config *Config) Enabled() bool {
+	return config.Exporter != ExporterNone
+}
+
+// Init installs the global tracer provider and the W3C trace context
+// propagator. The returned function flushes pending spans and must be called
+// before exiting.
+func Init(ctx context.Context, service string, config *Config) (func(context.Context) error, error) {
+	if !config.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	var closer io.Closer
+	switch config.Exporter {
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{
+			otlptracegrpc.WithEndpoint(config.Endpoint),
+			otlptracegrpc.WithTimeout(10 * time.Second),
+		}
+		if config.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		e, err := otlptracegrpc.New(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		exporter = e
+	case ExporterStdout:
+		e, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, err
+		}
+		exporter = e
+	case ExporterFile:
+		if config.File == "" {
+			return nil, fmt.Errorf("tracing: file is required by the file exporter")
+		}
+		f, err := os.OpenFile(config.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		e, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		exporter, closer = e, f
+	default:
+		return nil, fmt.Errorf("tracing: unknown exporter %q", config.Exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(service))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			if cerr := closer.Close(); err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}, nil
+}